@@ -0,0 +1,114 @@
+package sendgrid
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MaxAttachmentSize is the largest attachment, in bytes before base64
+// encoding, that fits in a message of MaxMailSize.
+const MaxAttachmentSize = MaxMailSize / 4 * 3
+
+// Attachment dispositions.
+const (
+	DispositionAttachment = "attachment"
+	DispositionInline     = "inline"
+)
+
+// sniffLen is the number of bytes used to detect the content type.
+const sniffLen = 512
+
+// NewAttachmentFromFile reads the file at path into an attachment named after
+// the file. The content type is detected from the file extension, falling
+// back to the content. Files larger than MaxAttachmentSize are rejected
+// without being read.
+func NewAttachmentFromFile(path string) (*Attachment, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Size() > MaxAttachmentSize {
+		return nil, attachmentTooLarge(filepath.Base(path))
+	}
+	return newAttachment(f, filepath.Base(path), DispositionAttachment, "")
+}
+
+// NewAttachmentFromReader reads r into an attachment named filename. The
+// content is base64 encoded while it is read. The content type is detected
+// from the extension of filename, falling back to the content. It returns an
+// error if r yields more than MaxAttachmentSize bytes.
+func NewAttachmentFromReader(r io.Reader, filename string) (*Attachment, error) {
+	return newAttachment(r, filename, DispositionAttachment, "")
+}
+
+// NewInlineAttachment is like NewAttachmentFromReader but returns an inline
+// attachment that HTML content refers to as "cid:<contentID>".
+func NewInlineAttachment(r io.Reader, filename, contentID string) (*Attachment, error) {
+	if contentID == "" {
+		return nil, fmt.Errorf("content ID of inline attachment %q must not be empty", filename)
+	}
+	return newAttachment(r, filename, DispositionInline, contentID)
+}
+
+func newAttachment(r io.Reader, filename, disposition, contentID string) (*Attachment, error) {
+	if filename == "" {
+		return nil, fmt.Errorf("attachment filename must not be empty")
+	}
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	head = head[:n]
+
+	var b strings.Builder
+	enc := base64.NewEncoder(base64.StdEncoding, &b)
+	if _, err := enc.Write(head); err != nil {
+		return nil, err
+	}
+	// read one byte more than allowed to detect oversized content
+	m, err := io.Copy(enc, io.LimitReader(r, MaxAttachmentSize-int64(n)+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(n)+m > MaxAttachmentSize {
+		return nil, attachmentTooLarge(filename)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+
+	return &Attachment{
+		Content:     b.String(),
+		Type:        detectContentType(filename, head),
+		Filename:    filename,
+		Disposition: disposition,
+		ContentID:   contentID,
+	}, nil
+}
+
+// detectContentType returns the MIME type registered for the extension of
+// filename, or the type sniffed from head.
+func detectContentType(filename string, head []byte) string {
+	if t := mime.TypeByExtension(filepath.Ext(filename)); t != "" {
+		return t
+	}
+	return http.DetectContentType(head)
+}
+
+func attachmentTooLarge(filename string) error {
+	return fmt.Errorf("attachment %q exceeds the limit of %d bytes", filename, MaxAttachmentSize)
+}
@@ -0,0 +1,84 @@
+package sendgrid
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAttachmentFromReader(t *testing.T) {
+	content := []byte("%PDF-1.4 report")
+
+	a, err := NewAttachmentFromReader(bytes.NewReader(content), "report.pdf")
+	assert.Nil(t, err)
+	assert.Equal(t, &Attachment{
+		Content:     base64.StdEncoding.EncodeToString(content),
+		Type:        "application/pdf",
+		Filename:    "report.pdf",
+		Disposition: "attachment",
+	}, a)
+}
+
+func TestNewAttachmentFromReader_sniff(t *testing.T) {
+	content := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 1024)...)
+
+	a, err := NewAttachmentFromReader(bytes.NewReader(content), "logo")
+	assert.Nil(t, err)
+	assert.Equal(t, "image/png", a.Type)
+	assert.Equal(t, base64.StdEncoding.EncodeToString(content), a.Content)
+}
+
+func TestNewAttachmentFromReader_empty(t *testing.T) {
+	a, err := NewAttachmentFromReader(strings.NewReader(""), "empty.txt")
+	assert.Nil(t, err)
+	assert.Equal(t, "", a.Content)
+	assert.Equal(t, "text/plain; charset=utf-8", a.Type)
+
+	_, err = NewAttachmentFromReader(strings.NewReader("x"), "")
+	assert.NotNil(t, err)
+}
+
+func TestNewAttachmentFromReader_tooLarge(t *testing.T) {
+	r := io.LimitReader(zeroReader{}, MaxAttachmentSize+1)
+
+	_, err := NewAttachmentFromReader(r, "large.bin")
+	assert.EqualError(t, err, `attachment "large.bin" exceeds the limit of 23592960 bytes`)
+}
+
+func TestNewInlineAttachment(t *testing.T) {
+	a, err := NewInlineAttachment(strings.NewReader("GIF89a"), "pixel.gif", "pixel")
+	assert.Nil(t, err)
+	assert.Equal(t, "inline", a.Disposition)
+	assert.Equal(t, "pixel", a.ContentID)
+	assert.Equal(t, "image/gif", a.Type)
+
+	_, err = NewInlineAttachment(strings.NewReader("GIF89a"), "pixel.gif", "")
+	assert.NotNil(t, err)
+}
+
+func TestNewAttachmentFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	assert.Nil(t, os.WriteFile(path, []byte("hello"), 0o600))
+
+	a, err := NewAttachmentFromFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "notes.txt", a.Filename)
+	assert.Equal(t, "aGVsbG8=", a.Content)
+	assert.Equal(t, "text/plain; charset=utf-8", a.Type)
+
+	_, err = NewAttachmentFromFile(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.NotNil(t, err)
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
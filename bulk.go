@@ -0,0 +1,207 @@
+package sendgrid
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"sync"
+)
+
+// defaultBulkConcurrency is the number of requests a BulkSender sends at the
+// same time when Concurrency is not set.
+const defaultBulkConcurrency = 4
+
+// BulkSender sends a message to more recipients than a single mail send
+// request allows. The recipients are split into chunks that respect
+// MaxPersonalizations and MaxRecipients, and each chunk is sent with
+// SendMail.
+//
+//	s := sendgrid.NewBulkSender(client)
+//	report, err := s.Send(ctx, newsletter, recipients)
+type BulkSender struct {
+	client *Client
+
+	// Concurrency is the maximum number of requests in flight. Zero means 4.
+	Concurrency int
+	// ChunkSize is the maximum number of personalizations per request. Zero
+	// or a value above MaxPersonalizations means MaxPersonalizations.
+	ChunkSize int
+}
+
+// NewBulkSender returns a BulkSender that sends with c.
+func NewBulkSender(c *Client) *BulkSender {
+	return &BulkSender{client: c}
+}
+
+// BulkChunk is the result of sending one chunk of recipients.
+type BulkChunk struct {
+	// Index is the position of the chunk in the recipient stream.
+	Index int
+	// Personalizations is the number of personalizations in the chunk.
+	Personalizations int
+	// Recipients is the number of to, cc and bcc addresses in the chunk.
+	Recipients int
+	// MessageID is the X-Message-Id of an accepted chunk.
+	MessageID string
+	// Err is the error returned by SendMail for a failed chunk.
+	Err error
+}
+
+// BulkReport is returned by (*BulkSender).Send.
+type BulkReport struct {
+	// BatchID is the batch ID shared by all chunks.
+	BatchID string
+	// Chunks holds the result of every chunk that was sent, in stream order.
+	Chunks []BulkChunk
+}
+
+// Failed returns the chunks that were not accepted.
+func (r *BulkReport) Failed() []BulkChunk {
+	var failed []BulkChunk
+	for _, c := range r.Chunks {
+		if c.Err != nil {
+			failed = append(failed, c)
+		}
+	}
+	return failed
+}
+
+// MessageIDs returns the message IDs of the accepted chunks.
+func (r *BulkReport) MessageIDs() []string {
+	var ids []string
+	for _, c := range r.Chunks {
+		if c.Err == nil {
+			ids = append(ids, c.MessageID)
+		}
+	}
+	return ids
+}
+
+// Send sends base to every personalization yielded by recipients. base
+// provides everything but the personalizations, which must be empty. All
+// chunks share the BatchID of base; if base has none, Send creates one with
+// CreateBatchID before sending the first chunk, so that the whole send can
+// be paused or cancelled as a unit.
+//
+// A failed chunk does not stop the others; its error is recorded in the
+// report. Send returns an error only if base is invalid, the batch ID cannot
+// be created or ctx is done. If ctx is done, the report holds the chunks sent
+// so far and the rest of the stream is not consumed.
+func (s *BulkSender) Send(ctx context.Context, base *InputSendMail, recipients iter.Seq[*Personalization]) (*BulkReport, error) {
+	if base == nil {
+		return nil, fmt.Errorf("base message must not be nil")
+	}
+	if len(base.Personalizations) > 0 {
+		return nil, fmt.Errorf("base message must not have personalizations; pass them as recipients")
+	}
+	if base.BatchID == "" {
+		batch, err := s.client.CreateBatchID(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create batch id: %w", err)
+		}
+		withBatch := *base
+		withBatch.BatchID = batch.BatchID
+		base = &withBatch
+	}
+
+	concurrency := s.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultBulkConcurrency
+	}
+
+	var (
+		wg     sync.WaitGroup
+		sem    = make(chan struct{}, concurrency)
+		chunks []*BulkChunk
+		err    error
+	)
+	send := func(ps []*Personalization) bool {
+		if err = ctx.Err(); err != nil {
+			return false
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			err = ctx.Err()
+			return false
+		}
+		chunk := &BulkChunk{
+			Index:            len(chunks),
+			Personalizations: len(ps),
+			Recipients:       countRecipients(ps),
+		}
+		chunks = append(chunks, chunk)
+
+		m := *base
+		m.Personalizations = ps
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			out, err := s.client.SendMail(ctx, &m)
+			if err != nil {
+				chunk.Err = err
+				return
+			}
+			chunk.MessageID = out.MessageID
+		}()
+		return true
+	}
+
+	for ps := range s.chunks(recipients) {
+		if !send(ps) {
+			break
+		}
+	}
+	wg.Wait()
+
+	report := &BulkReport{BatchID: base.BatchID, Chunks: make([]BulkChunk, len(chunks))}
+	for i, c := range chunks {
+		report.Chunks[i] = *c
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	return report, err
+}
+
+// chunks groups recipients into slices that fit in a single request.
+// Personalizations that exceed MaxRecipients on their own are sent alone and
+// rejected by the API.
+func (s *BulkSender) chunks(recipients iter.Seq[*Personalization]) iter.Seq[[]*Personalization] {
+	size := s.ChunkSize
+	if size <= 0 || size > MaxPersonalizations {
+		size = MaxPersonalizations
+	}
+	return func(yield func([]*Personalization) bool) {
+		var chunk []*Personalization
+		n := 0
+		for p := range recipients {
+			if p == nil {
+				continue
+			}
+			r := countRecipients([]*Personalization{p})
+			if len(chunk) > 0 && (len(chunk) == size || n+r > MaxRecipients) {
+				if !yield(chunk) {
+					return
+				}
+				chunk, n = nil, 0
+			}
+			chunk = append(chunk, p)
+			n += r
+		}
+		if len(chunk) > 0 {
+			yield(chunk)
+		}
+	}
+}
+
+func countRecipients(ps []*Personalization) int {
+	n := 0
+	for _, p := range ps {
+		n += len(p.To) + len(p.Cc) + len(p.Bcc)
+	}
+	return n
+}
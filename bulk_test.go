@@ -0,0 +1,212 @@
+package sendgrid
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func bulkRecipients(n int) iter.Seq[*Personalization] {
+	return func(yield func(*Personalization) bool) {
+		for i := range n {
+			p := NewPersonalization()
+			p.AddTo(NewEmail(fmt.Sprintf("user%d@example.com", i), ""))
+			if !yield(p) {
+				return
+			}
+		}
+	}
+}
+
+func bulkBase() *InputSendMail {
+	m := NewInputSendMail()
+	m.SetFrom(NewEmail("news@example.com", ""))
+	m.SetTemplateID("d-123")
+	m.BatchID = "batch-1"
+	return m
+}
+
+func TestBulkSender_Send(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	var (
+		mu       sync.Mutex
+		sizes    []int
+		inFlight atomic.Int32
+		peak     atomic.Int32
+		calls    atomic.Int32
+	)
+	mux.HandleFunc("/mail/send", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPost)
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+
+		var m InputSendMail
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&m))
+		assert.Equal(t, "batch-1", m.BatchID)
+		assert.Equal(t, "d-123", m.TemplateID)
+		mu.Lock()
+		sizes = append(sizes, len(m.Personalizations))
+		mu.Unlock()
+
+		w.Header().Set("X-Message-Id", fmt.Sprintf("msg-%d", calls.Add(1)))
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	s := NewBulkSender(client)
+	s.Concurrency = 2
+	report, err := s.Send(context.Background(), bulkBase(), bulkRecipients(2500))
+	assert.Nil(t, err)
+	assert.Equal(t, "batch-1", report.BatchID)
+	assert.Len(t, report.Chunks, 3)
+	assert.Empty(t, report.Failed())
+	assert.Len(t, report.MessageIDs(), 3)
+	assert.ElementsMatch(t, []int{1000, 1000, 500}, sizes)
+	assert.LessOrEqual(t, peak.Load(), int32(2))
+	for i, c := range report.Chunks {
+		assert.Equal(t, i, c.Index)
+		assert.Equal(t, c.Personalizations, c.Recipients)
+	}
+	assert.Equal(t, 500, report.Chunks[2].Personalizations)
+}
+
+func TestBulkSender_Send_failure(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/mail/send", func(w http.ResponseWriter, r *http.Request) {
+		var m InputSendMail
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&m))
+		if m.Personalizations[0].To[0].Email == "user10@example.com" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"errors":[{"message":"bad request"}]}`)
+			return
+		}
+		w.Header().Set("X-Message-Id", m.Personalizations[0].To[0].Email)
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	s := NewBulkSender(client)
+	s.ChunkSize = 10
+	report, err := s.Send(context.Background(), bulkBase(), bulkRecipients(25))
+	assert.Nil(t, err)
+	assert.Len(t, report.Chunks, 3)
+	assert.Equal(t, []string{"user0@example.com", "user20@example.com"}, report.MessageIDs())
+
+	failed := report.Failed()
+	assert.Len(t, failed, 1)
+	assert.Equal(t, 1, failed[0].Index)
+	assert.NotNil(t, failed[0].Err)
+}
+
+func TestBulkSender_Send_createsBatchID(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	var created atomic.Int32
+	mux.HandleFunc("/mail/batch", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPost)
+		created.Add(1)
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"batch_id":"batch-new"}`)
+	})
+	mux.HandleFunc("/mail/send", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, int32(1), created.Load())
+		var m InputSendMail
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&m))
+		assert.Equal(t, "batch-new", m.BatchID)
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	base := bulkBase()
+	base.BatchID = ""
+	s := NewBulkSender(client)
+	s.ChunkSize = 10
+	report, err := s.Send(context.Background(), base, bulkRecipients(25))
+	assert.Nil(t, err)
+	assert.Equal(t, "batch-new", report.BatchID)
+	assert.Len(t, report.Chunks, 3)
+	assert.Empty(t, report.Failed())
+	assert.Equal(t, int32(1), created.Load())
+	assert.Empty(t, base.BatchID)
+}
+
+func TestBulkSender_Send_batchIDError(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/mail/batch", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"errors":[{"message":"bad request"}]}`)
+	})
+	mux.HandleFunc("/mail/send", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("no chunk should be sent without a batch id")
+	})
+
+	base := bulkBase()
+	base.BatchID = ""
+	report, err := NewBulkSender(client).Send(context.Background(), base, bulkRecipients(5))
+	assert.ErrorContains(t, err, "failed to create batch id")
+	assert.Nil(t, report)
+}
+
+func TestBulkSender_Send_invalid(t *testing.T) {
+	s := NewBulkSender(New("key"))
+
+	_, err := s.Send(context.Background(), nil, bulkRecipients(1))
+	assert.NotNil(t, err)
+
+	m := bulkBase()
+	m.AddPersonalization(NewPersonalization())
+	_, err = s.Send(context.Background(), m, bulkRecipients(1))
+	assert.NotNil(t, err)
+}
+
+func TestBulkSender_Send_canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	report, err := NewBulkSender(New("key")).Send(ctx, bulkBase(), bulkRecipients(10))
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, report.Chunks)
+}
+
+func TestBulkSender_chunks(t *testing.T) {
+	p := func(to, cc int) *Personalization {
+		p := NewPersonalization()
+		for i := range to {
+			p.AddTo(NewEmail(fmt.Sprintf("to%d@example.com", i), ""))
+		}
+		for i := range cc {
+			p.AddCc(NewEmail(fmt.Sprintf("cc%d@example.com", i), ""))
+		}
+		return p
+	}
+	recipients := func(yield func(*Personalization) bool) {
+		for _, p := range []*Personalization{p(1, 499), p(1, 499), p(1, 1), nil, p(1200, 0), p(1, 0)} {
+			if !yield(p) {
+				return
+			}
+		}
+	}
+
+	var sizes [][2]int
+	for ps := range NewBulkSender(nil).chunks(recipients) {
+		sizes = append(sizes, [2]int{len(ps), countRecipients(ps)})
+	}
+	assert.Equal(t, [][2]int{{2, 1000}, {1, 2}, {1, 1200}, {1, 1}}, sizes)
+}
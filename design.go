@@ -3,6 +3,8 @@ package sendgrid
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"strconv"
 )
 
 type Design struct {
@@ -28,7 +30,35 @@ type OutputGetDesigns struct {
 
 // see: https://docs.sendgrid.com/api-reference/designs-api/list-designs
 func (c *Client) GetDesigns(ctx context.Context) (*OutputGetDesigns, error) {
-	req, err := c.NewRequest("GET", "/designs", nil)
+	return c.GetDesignsPage(withDefaultOperationName(ctx, "GetDesigns"), nil)
+}
+
+type InputGetDesigns struct {
+	PageSize  int
+	PageToken string
+	Summary   *bool
+}
+
+// GetDesignsPage is like GetDesigns but accepts paging parameters.
+// see: https://docs.sendgrid.com/api-reference/designs-api/list-designs
+func (c *Client) GetDesignsPage(ctx context.Context, input *InputGetDesigns) (*OutputGetDesigns, error) {
+	u, _ := url.Parse("/designs")
+
+	if input != nil {
+		q := u.Query()
+		if input.PageSize > 0 {
+			q.Set("page_size", strconv.Itoa(input.PageSize))
+		}
+		if input.PageToken != "" {
+			q.Set("page_token", input.PageToken)
+		}
+		if input.Summary != nil {
+			q.Set("summary", strconv.FormatBool(*input.Summary))
+		}
+		u.RawQuery = q.Encode()
+	}
+
+	req, err := c.NewRequest("GET", u.String(), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,156 @@
+package sendgrid
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"regexp"
+
+	"github.com/i10416/sendgrid/internal/redact"
+)
+
+// defaultDumpBodySize is the number of body bytes dumped when DumpOptions
+// does not set MaxBodySize.
+const defaultDumpBodySize = 8 << 10
+
+const redactedValue = "[REDACTED]"
+
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+// DumpOptions controls the request and response dumps logged in debug mode.
+//
+// The API key in the Authorization header, secret fields such as password and
+// oauth_client_secret, and the base64 content of mail attachments are always
+// redacted.
+type DumpOptions struct {
+	// MaxBodySize is the maximum number of body bytes dumped. Zero means
+	// 8 KiB; a negative value disables the limit.
+	MaxBodySize int
+	// RedactEmails replaces email addresses in the URL and body.
+	RedactEmails bool
+}
+
+// OptionDebugDump configures the request and response dumps logged when
+// debugging is enabled with OptionDebug.
+func OptionDebugDump(opts *DumpOptions) func(*Client) {
+	return func(c *Client) {
+		c.dump = opts
+	}
+}
+
+func (o *DumpOptions) maxBodySize() int {
+	if o == nil || o.MaxBodySize == 0 {
+		return defaultDumpBodySize
+	}
+	return o.MaxBodySize
+}
+
+func (o *DumpOptions) redactEmails() bool {
+	return o != nil && o.RedactEmails
+}
+
+// logRequest dumps req with secrets redacted. The body of req is left intact.
+func logRequest(req *http.Request, d debug, opts *DumpOptions) error {
+	if !d.Debug() {
+		return nil
+	}
+
+	body, err := peekRequestBody(req)
+	if err != nil {
+		return err
+	}
+
+	r := req.Clone(req.Context())
+	if r.Header.Get("Authorization") != "" {
+		r.Header.Set("Authorization", "Bearer "+redactedValue)
+	}
+	if opts.redactEmails() {
+		u := *r.URL
+		u.Path = emailPattern.ReplaceAllString(u.Path, redactedValue)
+		u.RawPath = ""
+		q, _ := url.QueryUnescape(u.RawQuery)
+		u.RawQuery = emailPattern.ReplaceAllString(q, redactedValue)
+		r.URL = &u
+	}
+	b := redactDump(body, opts)
+	r.Body = io.NopCloser(bytes.NewReader(b))
+	r.ContentLength = int64(len(b))
+
+	text, err := httputil.DumpRequestOut(r, true)
+	if err != nil {
+		return err
+	}
+	d.Debugln(string(text))
+	return nil
+}
+
+// peekRequestBody returns a copy of the body of req.
+func peekRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody == nil {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		return body, nil
+	}
+	rc, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// redactDump redacts secrets from a body and applies the size limit of opts.
+func redactDump(body []byte, opts *DumpOptions) []byte {
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err == nil {
+		v = redact.Secrets(v, redactedValue)
+		if b, err := json.Marshal(redactDumpValue("", v, opts)); err == nil {
+			body = b
+		}
+	} else if opts.redactEmails() {
+		body = emailPattern.ReplaceAll(body, []byte(redactedValue))
+	}
+
+	if limit := opts.maxBodySize(); limit > 0 && len(body) > limit {
+		return fmt.Appendf(body[:limit:limit], "... (%d bytes truncated)", len(body)-limit)
+	}
+	return body
+}
+
+// redactDumpValue shortens attachment content and, if opts asks for it,
+// redacts email addresses in v.
+func redactDumpValue(key string, v interface{}, opts *DumpOptions) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			switch {
+			case key == "attachments" && k == "content":
+				if s, ok := e.(string); ok {
+					v[k] = fmt.Sprintf("[%d bytes of base64]", len(s))
+				}
+			default:
+				v[k] = redactDumpValue(k, e, opts)
+			}
+		}
+	case []interface{}:
+		for i, e := range v {
+			// elements of an array are checked against the key of the array
+			v[i] = redactDumpValue(key, e, opts)
+		}
+	case string:
+		if opts.redactEmails() {
+			return emailPattern.ReplaceAllString(v, redactedValue)
+		}
+	}
+	return v
+}
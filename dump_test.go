@@ -0,0 +1,89 @@
+package sendgrid
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogRequest(t *testing.T) {
+	client := New("secret-api-key", OptionBaseURL("https://api.sendgrid.com/v3"))
+	debug := &mockDebug{debug: true}
+
+	mail := NewInputSendMail()
+	mail.SetFrom(NewEmail("from@example.com", ""))
+	mail.AddAttachment(&Attachment{Content: "aGVsbG8gd29ybGQ=", Filename: "hello.txt"})
+	req, err := client.NewRequest("POST", "/mail/send", mail)
+	assert.NoError(t, err)
+
+	assert.NoError(t, logRequest(req, debug, nil))
+	if assert.Len(t, debug.logs, 1) {
+		dump := debug.logs[0]
+		assert.Contains(t, dump, "POST /v3/mail/send HTTP/1.1")
+		assert.Contains(t, dump, "Authorization: Bearer [REDACTED]")
+		assert.NotContains(t, dump, "secret-api-key")
+		assert.NotContains(t, dump, "aGVsbG8gd29ybGQ=")
+		assert.Contains(t, dump, "[16 bytes of base64]")
+		assert.Contains(t, dump, "from@example.com")
+	}
+
+	// the request body is left intact
+	body, err := peekRequestBody(req)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), "aGVsbG8gd29ybGQ=")
+}
+
+func TestLogRequestRedactEmails(t *testing.T) {
+	client := New("secret-api-key", OptionBaseURL("https://api.sendgrid.com/v3"))
+	debug := &mockDebug{debug: true}
+
+	req, err := client.NewRequest("DELETE", "/suppression/bounces", &InputDeleteSuppressions{Emails: []string{"a@example.com"}})
+	assert.NoError(t, err)
+	req.URL.RawQuery = "email=b%40example.com"
+
+	assert.NoError(t, logRequest(req, debug, &DumpOptions{RedactEmails: true}))
+	if assert.Len(t, debug.logs, 1) {
+		assert.NotContains(t, debug.logs[0], "example.com")
+		assert.Contains(t, debug.logs[0], `{"emails":["[REDACTED]"]}`)
+	}
+}
+
+func TestRedactDump(t *testing.T) {
+	body := []byte(`{"name": "hook", "oauth_client_secret": "s3cr3t", "password": ""}`)
+	assert.Equal(t, `{"name":"hook","oauth_client_secret":"[REDACTED]","password":""}`, string(redactDump(body, nil)))
+
+	long := []byte(strings.Repeat("a", 20))
+	assert.Equal(t, "aaaaaaaaaa... (10 bytes truncated)", string(redactDump(long, &DumpOptions{MaxBodySize: 10})))
+	assert.Equal(t, long, redactDump(long, &DumpOptions{MaxBodySize: -1}))
+}
+
+func TestDebugDump(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	var buf bytes.Buffer
+	OptionDebug(true)(client)
+	OptionLog(log.New(&buf, "", 0))(client)
+	OptionDebugDump(&DumpOptions{RedactEmails: true})(client)
+
+	mux.HandleFunc("/teammates", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"token": "abc", "email": "new@example.com", "is_admin": false}`)
+	})
+
+	got, err := client.InviteTeammate(context.TODO(), &InputInviteTeammate{Email: "new@example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, "new@example.com", got.Email)
+
+	out := buf.String()
+	assert.Contains(t, out, "POST /v3/teammates HTTP/1.1")
+	assert.Contains(t, out, "HTTP/1.1 200 OK")
+	assert.Contains(t, out, `"token":"abc"`)
+	assert.NotContains(t, out, "test-token")
+	assert.NotContains(t, out, "new@example.com")
+}
@@ -0,0 +1,306 @@
+package sendgrid
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WriteEML writes the mail that m sends to its i-th personalization as an
+// RFC 5322 message with MIME parts, suitable for a .eml file.
+//
+// Subject, headers, send_at and substitutions of the personalization
+// override those of m. Content becomes a multipart/alternative body, inline
+// attachments are grouped with the HTML content in multipart/related, and
+// other attachments are added in multipart/mixed. Bcc recipients are kept in
+// a Bcc header. The Date header is the send_at time if set, or the current
+// time.
+//
+// Mail that relies on template_id has no content to export; render it with
+// RenderTemplateVersion and set Content first.
+func (m *InputSendMail) WriteEML(w io.Writer, i int) error {
+	h, content, err := m.message(i)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	writeMIMEHeader(&buf, h)
+	buf.Write(content)
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// message returns the header and body of the mail that m sends to its i-th
+// personalization.
+func (m *InputSendMail) message(i int) (textproto.MIMEHeader, []byte, error) {
+	if i < 0 || i >= len(m.Personalizations) || m.Personalizations[i] == nil {
+		return nil, nil, fmt.Errorf("personalization %d does not exist", i)
+	}
+	if len(m.Content) == 0 {
+		return nil, nil, fmt.Errorf("mail has no content to export")
+	}
+	p := m.Personalizations[i]
+	sub := emlSubstituter(p.Substitutions)
+
+	h := make(textproto.MIMEHeader)
+	if m.From != nil {
+		h.Set("From", formatAddress(m.From.Email, m.From.Name))
+	}
+	if to := formatAddressList(p.To); to != "" {
+		h.Set("To", to)
+	}
+	if cc := formatAddressList(p.Cc); cc != "" {
+		h.Set("Cc", cc)
+	}
+	if bcc := formatAddressList(p.Bcc); bcc != "" {
+		h.Set("Bcc", bcc)
+	}
+	switch {
+	case m.ReplyTo != nil:
+		h.Set("Reply-To", formatAddress(m.ReplyTo.Email, m.ReplyTo.Name))
+	case len(m.ReplyToList) > 0:
+		list := make([]string, len(m.ReplyToList))
+		for i, r := range m.ReplyToList {
+			list[i] = formatAddress(r.Email, r.Name)
+		}
+		h.Set("Reply-To", strings.Join(list, ", "))
+	}
+	subject := m.Subject
+	if p.Subject != "" {
+		subject = p.Subject
+	}
+	h.Set("Subject", mime.QEncoding.Encode("utf-8", sub.Replace(subject)))
+
+	date := time.Now()
+	if p.SendAt != 0 {
+		date = time.Unix(p.SendAt, 0)
+	} else if m.SendAt != 0 {
+		date = time.Unix(m.SendAt, 0)
+	}
+	h.Set("Date", date.Format(time.RFC1123Z))
+	h.Set("MIME-Version", "1.0")
+
+	headers := map[string]string{}
+	for k, v := range m.Headers {
+		headers[k] = v
+	}
+	for k, v := range p.Headers {
+		headers[k] = v
+	}
+	for k, v := range headers {
+		if h.Get(k) == "" {
+			h.Set(k, mime.QEncoding.Encode("utf-8", sub.Replace(v)))
+		}
+	}
+
+	header, content, err := emlBody(m, sub).encode()
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, v := range header {
+		h[k] = v
+	}
+	return h, content, nil
+}
+
+// EML returns the mail that m sends to its i-th personalization as an
+// RFC 5322 message. See WriteEML.
+func (m *InputSendMail) EML(i int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := m.WriteEML(&buf, i); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// mimeEntity is a MIME leaf part with a body, or a multipart entity.
+type mimeEntity struct {
+	header  textproto.MIMEHeader
+	body    []byte
+	subtype string
+	parts   []*mimeEntity
+}
+
+// encode returns the header and encoded body of e.
+func (e *mimeEntity) encode() (textproto.MIMEHeader, []byte, error) {
+	if e.parts == nil {
+		return e.header, e.body, nil
+	}
+	if len(e.parts) == 1 {
+		return e.parts[0].encode()
+	}
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for _, part := range e.parts {
+		header, body, err := part.encode()
+		if err != nil {
+			return nil, nil, err
+		}
+		w, err := mw.CreatePart(header)
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, err := w.Write(body); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, nil, err
+	}
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", mime.FormatMediaType("multipart/"+e.subtype, map[string]string{"boundary": mw.Boundary()}))
+	return header, buf.Bytes(), nil
+}
+
+// emlBody builds the MIME structure of m:
+//
+//	multipart/mixed
+//	  multipart/related
+//	    multipart/alternative (text/plain, text/html, ...)
+//	    inline attachments
+//	  attachments
+//
+// Levels with a single part are collapsed.
+func emlBody(m *InputSendMail, sub *strings.Replacer) *mimeEntity {
+	alternative := &mimeEntity{subtype: "alternative"}
+	for _, c := range m.Content {
+		if c == nil {
+			continue
+		}
+		alternative.parts = append(alternative.parts, emlTextPart(c, sub))
+	}
+
+	related := &mimeEntity{subtype: "related", parts: []*mimeEntity{alternative}}
+	mixed := &mimeEntity{subtype: "mixed", parts: []*mimeEntity{related}}
+	for _, a := range m.Attachments {
+		if a == nil {
+			continue
+		}
+		part := emlAttachmentPart(a)
+		if a.Disposition == DispositionInline && a.ContentID != "" {
+			related.parts = append(related.parts, part)
+		} else {
+			mixed.parts = append(mixed.parts, part)
+		}
+	}
+	return mixed
+}
+
+func emlTextPart(c *Content, sub *strings.Replacer) *mimeEntity {
+	contentType := c.Type
+	if mt, params, err := mime.ParseMediaType(c.Type); err == nil && strings.HasPrefix(mt, "text/") && params["charset"] == "" {
+		params["charset"] = "utf-8"
+		contentType = mime.FormatMediaType(mt, params)
+	}
+
+	var buf bytes.Buffer
+	qp := quotedprintable.NewWriter(&buf)
+	_, _ = qp.Write([]byte(sub.Replace(c.Value)))
+	_ = qp.Close()
+
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Type", contentType)
+	h.Set("Content-Transfer-Encoding", "quoted-printable")
+	return &mimeEntity{header: h, body: buf.Bytes()}
+}
+
+func emlAttachmentPart(a *Attachment) *mimeEntity {
+	contentType := a.Type
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(a.Filename))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+	}
+	disposition := a.Disposition
+	if disposition == "" {
+		disposition = DispositionAttachment
+	}
+
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Type", contentType)
+	h.Set("Content-Transfer-Encoding", "base64")
+	h.Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": a.Filename}))
+	if a.ContentID != "" {
+		h.Set("Content-ID", "<"+a.ContentID+">")
+	}
+
+	// the content is already base64; wrap it at 76 characters per line
+	var buf bytes.Buffer
+	content := a.Content
+	for len(content) > 76 {
+		buf.WriteString(content[:76])
+		buf.WriteString("\r\n")
+		content = content[76:]
+	}
+	buf.WriteString(content)
+	return &mimeEntity{header: h, body: buf.Bytes()}
+}
+
+// emlSubstituter replaces the legacy substitution tags of a personalization.
+func emlSubstituter(substitutions map[string]string) *strings.Replacer {
+	keys := make([]string, 0, len(substitutions))
+	for k := range substitutions {
+		keys = append(keys, k)
+	}
+	// replace longer tags first so that -name- does not shadow -name_full-
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+	pairs := make([]string, 0, 2*len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k, substitutions[k])
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+func formatAddress(email, name string) string {
+	return (&mail.Address{Name: name, Address: email}).String()
+}
+
+func formatAddressList(emails []*Email) string {
+	list := make([]string, 0, len(emails))
+	for _, e := range emails {
+		if e != nil {
+			list = append(list, formatAddress(e.Email, e.Name))
+		}
+	}
+	return strings.Join(list, ", ")
+}
+
+// emlHeaderOrder lists the headers written first, in this order. Other
+// headers follow in alphabetical order.
+var emlHeaderOrder = []string{"Date", "From", "To", "Cc", "Bcc", "Reply-To", "Subject", "MIME-Version", "Content-Type", "Content-Transfer-Encoding"}
+
+func writeMIMEHeader(buf *bytes.Buffer, h textproto.MIMEHeader) {
+	written := map[string]bool{}
+	write := func(k, name string) {
+		for _, v := range h[k] {
+			fmt.Fprintf(buf, "%s: %s\r\n", name, v)
+		}
+		written[k] = true
+	}
+	for _, name := range emlHeaderOrder {
+		write(textproto.CanonicalMIMEHeaderKey(name), name)
+	}
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		if !written[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		write(k, k)
+	}
+	buf.WriteString("\r\n")
+}
@@ -0,0 +1,374 @@
+package sendgrid
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// emlSkippedHeaders are the headers that ParseEML maps to fields of
+// InputSendMail, that SendGrid sets itself, or that record the delivery of
+// the original message and would be wrong on a new one.
+var emlSkippedHeaders = map[string]bool{
+	"From":                      true,
+	"To":                        true,
+	"Cc":                        true,
+	"Bcc":                       true,
+	"Reply-To":                  true,
+	"Subject":                   true,
+	"Date":                      true,
+	"Message-Id":                true,
+	"Mime-Version":              true,
+	"Content-Type":              true,
+	"Content-Transfer-Encoding": true,
+	"Content-Disposition":       true,
+	"Received":                  true,
+	"Return-Path":               true,
+	"Dkim-Signature":            true,
+	"Domainkey-Signature":       true,
+	"X-Sg-Id":                   true,
+	"X-Sg-Eid":                  true,
+	"Authentication-Results":    true,
+	"Received-Spf":              true,
+	"X-Received":                true,
+	"Delivered-To":              true,
+	"X-Original-To":             true,
+}
+
+// emlSkippedHeaderPrefixes are the prefixes of header families that are
+// skipped like emlSkippedHeaders, such as the ARC-Seal, ARC-Message-Signature
+// and ARC-Authentication-Results headers of RFC 8617.
+var emlSkippedHeaderPrefixes = []string{"Arc-"}
+
+// emlSkipHeader reports whether the canonical header key k is not copied to
+// Headers.
+func emlSkipHeader(k string) bool {
+	if emlSkippedHeaders[k] {
+		return true
+	}
+	for _, prefix := range emlSkippedHeaderPrefixes {
+		if strings.HasPrefix(k, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// emlExtensions are the file extensions of attachments without a filename,
+// for types with several registered extensions.
+var emlExtensions = map[string]string{
+	"text/plain":      ".txt",
+	"text/html":       ".html",
+	"text/calendar":   ".ics",
+	"image/jpeg":      ".jpg",
+	"application/pdf": ".pdf",
+}
+
+// ParseEML parses an RFC 5322 message, such as a .eml file, into an
+// InputSendMail. See NewInputSendMailFromMessage.
+func ParseEML(r io.Reader) (*InputSendMail, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, err
+	}
+	return NewInputSendMailFromMessage(msg)
+}
+
+// NewInputSendMailFromMessage converts msg into an InputSendMail with a
+// single personalization holding the To, Cc and Bcc recipients, so that mail
+// composed as RFC 5322 messages can be sent with SendMail.
+//
+// The first text/plain and text/html parts that are not attachments become
+// Content, converted to UTF-8. Other parts become attachments; parts with a
+// Content-ID inside multipart/related or with an inline disposition become
+// inline attachments. Custom headers such as X-Campaign are copied to
+// Headers. The Date header is ignored, so the mail is sent immediately.
+// Signatures and trace headers of the original delivery, such as
+// DKIM-Signature, ARC-* and Authentication-Results, are dropped, since they
+// do not hold for the new message.
+//
+// Supported charsets are UTF-8, US-ASCII, ISO-8859-1 and Windows-1252.
+// Converting others, such as ISO-2022-JP or Shift_JIS, would need
+// golang.org/x/text, which this package does not depend on. Instead, text
+// parts in other charsets are kept unconverted as attachments with their
+// charset in the type, and encoded words in other charsets are left encoded
+// in the subject, display names and headers.
+func NewInputSendMailFromMessage(msg *mail.Message) (*InputSendMail, error) {
+	m := NewInputSendMail()
+
+	from, err := emlAddresses(msg.Header, "From")
+	if err != nil {
+		return nil, err
+	}
+	if len(from) > 0 {
+		m.From = from[0]
+	}
+
+	p := NewPersonalization()
+	for _, f := range []struct {
+		key string
+		dst *[]*Email
+	}{{"To", &p.To}, {"Cc", &p.Cc}, {"Bcc", &p.Bcc}} {
+		if *f.dst, err = emlAddresses(msg.Header, f.key); err != nil {
+			return nil, err
+		}
+	}
+	m.AddPersonalization(p)
+
+	replyTo, err := emlAddresses(msg.Header, "Reply-To")
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case len(replyTo) == 1:
+		m.ReplyTo = &ReplyTo{Email: replyTo[0].Email, Name: replyTo[0].Name}
+	case len(replyTo) > 1:
+		for _, r := range replyTo {
+			m.ReplyToList = append(m.ReplyToList, &ReplyToList{Email: r.Email, Name: r.Name})
+		}
+	}
+
+	if m.Subject, err = emlHeaderDecoder.DecodeHeader(msg.Header.Get("Subject")); err != nil {
+		return nil, fmt.Errorf("subject: %w", err)
+	}
+
+	for k, v := range msg.Header {
+		k = textproto.CanonicalMIMEHeaderKey(k)
+		if emlSkipHeader(k) || len(v) == 0 {
+			continue
+		}
+		value, err := emlHeaderDecoder.DecodeHeader(v[0])
+		if err != nil {
+			return nil, fmt.Errorf("header %s: %w", k, err)
+		}
+		// an encoded CR or LF would inject header lines when the value is
+		// written out again
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, fmt.Errorf("header %s: decoded value contains a line break", k)
+		}
+		if m.Headers == nil {
+			m.Headers = map[string]string{}
+		}
+		m.Headers[k] = value
+	}
+
+	header := textproto.MIMEHeader(msg.Header)
+	if err := emlParsePart(m, header, msg.Body, false); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(m.Content, func(i, j int) bool {
+		return contentRank(m.Content[i].Type) < contentRank(m.Content[j].Type)
+	})
+	return m, nil
+}
+
+func emlAddresses(h mail.Header, key string) ([]*Email, error) {
+	if h.Get(key) == "" {
+		return nil, nil
+	}
+	parser := &mail.AddressParser{WordDecoder: emlHeaderDecoder}
+	list, err := parser.ParseList(h.Get(key))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", strings.ToLower(key), err)
+	}
+	emails := make([]*Email, len(list))
+	for i, a := range list {
+		emails[i] = NewEmail(a.Address, a.Name)
+	}
+	return emails, nil
+}
+
+// emlParsePart adds the content and attachments of a MIME part to m.
+// related reports whether the part is inside multipart/related.
+func emlParsePart(m *InputSendMail, h textproto.MIMEHeader, body io.Reader, related bool) error {
+	contentType := h.Get("Content-Type")
+	if contentType == "" {
+		contentType = "text/plain; charset=us-ascii"
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("content-type %q: %w", contentType, err)
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := emlParsePart(m, part.Header, part, related || mediaType == "multipart/related"); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := emlDecodeBody(h.Get("Content-Transfer-Encoding"), body)
+	if err != nil {
+		return err
+	}
+
+	disposition, dparams, _ := mime.ParseMediaType(h.Get("Content-Disposition"))
+	filename := dparams["filename"]
+	if filename == "" {
+		filename = params["name"]
+	}
+	if filename != "" {
+		if decoded, err := emlHeaderDecoder.DecodeHeader(filename); err == nil {
+			filename = decoded
+		}
+	}
+	contentID := strings.Trim(h.Get("Content-Id"), "<> ")
+
+	isText := mediaType == "text/plain" || mediaType == "text/html"
+	if isText && disposition != DispositionAttachment && filename == "" && !emlHasContent(m, mediaType) {
+		text, err := emlDecodeCharset(params["charset"], data)
+		switch {
+		case err == nil:
+			m.AddContent(NewContent(mediaType, text))
+			return nil
+		case !errors.Is(err, errUnsupportedCharset):
+			return err
+		}
+		// keep the part as it is, below, for the recipient to decode
+	}
+
+	attachmentType := mediaType
+	if charset := params["charset"]; charset != "" && strings.HasPrefix(mediaType, "text/") {
+		attachmentType = mime.FormatMediaType(mediaType, map[string]string{"charset": charset})
+	}
+	a := &Attachment{
+		Content:     base64.StdEncoding.EncodeToString(data),
+		Type:        attachmentType,
+		Filename:    filename,
+		Disposition: DispositionAttachment,
+	}
+	if contentID != "" && (related || disposition == DispositionInline) {
+		a.Disposition = DispositionInline
+		a.ContentID = contentID
+	}
+	if a.Filename == "" {
+		ext, ok := emlExtensions[mediaType]
+		if !ok {
+			ext = ".bin"
+			if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+				ext = exts[0]
+			}
+		}
+		a.Filename = fmt.Sprintf("attachment-%d%s", len(m.Attachments)+1, ext)
+	}
+	a.Filename = filepath.Base(a.Filename)
+	m.AddAttachment(a)
+	return nil
+}
+
+func emlHasContent(m *InputSendMail, mediaType string) bool {
+	for _, c := range m.Content {
+		if c.Type == mediaType {
+			return true
+		}
+	}
+	return false
+}
+
+func emlDecodeBody(encoding string, body io.Reader) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		// tolerate line breaks in the encoded body
+		b, err := io.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		b = bytes.Map(func(r rune) rune {
+			if r == '\r' || r == '\n' || r == ' ' || r == '\t' {
+				return -1
+			}
+			return r
+		}, b)
+		return base64.StdEncoding.DecodeString(string(b))
+	case "quoted-printable":
+		return io.ReadAll(quotedprintable.NewReader(body))
+	case "", "7bit", "8bit", "binary":
+		return io.ReadAll(body)
+	}
+	return nil, fmt.Errorf("unsupported content-transfer-encoding %q", encoding)
+}
+
+// emlDecodeCharset converts text in charset to UTF-8.
+func emlDecodeCharset(charset string, b []byte) (string, error) {
+	r, err := emlCharsetReader(charset, bytes.NewReader(b))
+	if err != nil {
+		return "", err
+	}
+	out, err := io.ReadAll(r)
+	return string(out), err
+}
+
+// windows1252 maps the bytes 0x80 to 0x9F of Windows-1252, where it differs
+// from ISO-8859-1.
+var windows1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8D, 'Ž', 0x8F,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9D, 'ž', 'Ÿ',
+}
+
+// errUnsupportedCharset is returned by emlCharsetReader for charsets it
+// cannot convert.
+var errUnsupportedCharset = errors.New("unsupported charset")
+
+// emlHeaderDecoder decodes the encoded words of headers. Words in
+// unsupported charsets are left encoded instead of failing.
+var emlHeaderDecoder = &mime.WordDecoder{CharsetReader: emlHeaderCharsetReader}
+
+// emlHeaderCharsetReader is like emlCharsetReader, but re-encodes input in
+// an unsupported charset as an encoded word, so that the header keeps it
+// unchanged.
+func emlHeaderCharsetReader(charset string, input io.Reader) (io.Reader, error) {
+	r, err := emlCharsetReader(charset, input)
+	if !errors.Is(err, errUnsupportedCharset) {
+		return r, err
+	}
+	b, err := io.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+	return strings.NewReader("=?" + charset + "?B?" + base64.StdEncoding.EncodeToString(b) + "?="), nil
+}
+
+// emlCharsetReader returns a reader that converts input in charset to
+// UTF-8.
+func emlCharsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(strings.TrimSpace(charset)) {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		return input, nil
+	case "iso-8859-1", "latin1", "windows-1252", "cp1252":
+		b, err := io.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		cp1252 := strings.HasSuffix(strings.ToLower(charset), "1252")
+		out := make([]byte, 0, len(b))
+		for _, c := range b {
+			r := rune(c)
+			if cp1252 && c >= 0x80 && c <= 0x9F {
+				r = windows1252[c-0x80]
+			}
+			out = utf8.AppendRune(out, r)
+		}
+		return bytes.NewReader(out), nil
+	}
+	return nil, fmt.Errorf("%w %q", errUnsupportedCharset, charset)
+}
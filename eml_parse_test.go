@@ -0,0 +1,173 @@
+package sendgrid
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEML_roundTrip(t *testing.T) {
+	b, err := emlTestMail().EML(0)
+	assert.Nil(t, err)
+
+	m, err := ParseEML(bytes.NewReader(b))
+	assert.Nil(t, err)
+	assert.Equal(t, NewEmail("from@example.com", "Shop"), m.From)
+	assert.Equal(t, &ReplyTo{Email: "support@example.com"}, m.ReplyTo)
+	assert.Equal(t, "Order #42", m.Subject)
+	assert.Equal(t, map[string]string{"X-Campaign": "spring"}, m.Headers)
+	assert.Equal(t, []*Personalization{{
+		To:  []*Email{NewEmail("ann@example.com", "Ann Müller")},
+		Bcc: []*Email{NewEmail("audit@example.com", "")},
+	}}, m.Personalizations)
+	assert.Equal(t, []*Content{
+		NewContent("text/plain", "Hello Ann, your order #42 is ready."),
+		NewContent("text/html", `<p>Hello Ann, <img src="cid:logo"></p>`),
+	}, m.Content)
+	assert.Equal(t, []*Attachment{
+		{Content: base64.StdEncoding.EncodeToString([]byte("GIF89a")), Type: "image/gif", Filename: "logo.gif", Disposition: "inline", ContentID: "logo"},
+		{Content: base64.StdEncoding.EncodeToString([]byte("%PDF-1.4")), Type: "application/pdf", Filename: "invoice.pdf", Disposition: "attachment"},
+	}, m.Attachments)
+	assert.Nil(t, m.Validate())
+}
+
+func TestParseEML_legacy(t *testing.T) {
+	raw := strings.Join([]string{
+		"From: =?ISO-8859-1?Q?Andr=E9?= <andre@example.com>",
+		"To: a@example.com, \"B\" <b@example.com>",
+		"Reply-To: r1@example.com, r2@example.com",
+		"Subject: =?windows-1252?Q?=93Caf=E9=94?=",
+		"Message-ID: <1@example.com>",
+		"X-Mailer: legacy",
+		"Content-Type: multipart/mixed; boundary=outer",
+		"",
+		"--outer",
+		"Content-Type: text/html; charset=iso-8859-1",
+		"Content-Transfer-Encoding: quoted-printable",
+		"",
+		"<p>Caf=E9</p>",
+		"--outer",
+		"Content-Type: text/plain; charset=windows-1252",
+		"",
+		"Price: \x8010",
+		"--outer",
+		"Content-Type: text/plain",
+		"",
+		"second body",
+		"--outer",
+		"Content-Type: application/octet-stream; name=\"data.bin\"",
+		"Content-Transfer-Encoding: base64",
+		"",
+		"AAEC",
+		"AwQ=",
+		"--outer--",
+		"",
+	}, "\r\n")
+
+	m, err := ParseEML(strings.NewReader(raw))
+	assert.Nil(t, err)
+	assert.Equal(t, NewEmail("andre@example.com", "André"), m.From)
+	assert.Equal(t, "“Café”", m.Subject)
+	assert.Equal(t, []*Email{NewEmail("a@example.com", ""), NewEmail("b@example.com", "B")}, m.Personalizations[0].To)
+	assert.Nil(t, m.ReplyTo)
+	assert.Len(t, m.ReplyToList, 2)
+	assert.Equal(t, map[string]string{"X-Mailer": "legacy"}, m.Headers)
+	assert.Equal(t, []*Content{
+		NewContent("text/plain", "Price: €10"),
+		NewContent("text/html", "<p>Café</p>"),
+	}, m.Content)
+	if assert.Len(t, m.Attachments, 2) {
+		assert.Equal(t, "attachment-1.txt", m.Attachments[0].Filename)
+		assert.Equal(t, "text/plain", m.Attachments[0].Type)
+		assert.Equal(t, "data.bin", m.Attachments[1].Filename)
+		assert.Equal(t, "AAECAwQ=", m.Attachments[1].Content)
+	}
+}
+
+func TestParseEML_signed(t *testing.T) {
+	raw := strings.Join([]string{
+		"Received: from mail.example.com by mx.example.net; Mon, 2 Jun 2025 10:00:00 +0000",
+		"ARC-Seal: i=1; a=rsa-sha256; t=1748858400; cv=none; d=example.net; s=arc;",
+		"\tb=c2VhbA==",
+		"ARC-Message-Signature: i=1; a=rsa-sha256; c=relaxed/relaxed; d=example.net;",
+		"\ts=arc; h=from:to:subject; bh=Ym9keQ==; b=c2ln",
+		"ARC-Authentication-Results: i=1; mx.example.net; dkim=pass header.d=example.com",
+		"Authentication-Results: mx.example.net; dkim=pass header.d=example.com;",
+		"\tspf=pass smtp.mailfrom=example.com",
+		"Received-SPF: pass (mx.example.net: domain of example.com designates 192.0.2.1 as permitted sender)",
+		"DKIM-Signature: v=1; a=rsa-sha256; c=relaxed/relaxed; d=example.com;",
+		"\ts=s1; h=from:to:subject:x-campaign; bh=Ym9keQ==;",
+		"\tb=c2lnbmF0dXJl",
+		"X-SG-EID: dGVzdA==",
+		"X-SG-ID: dGVzdA==",
+		"From: shop@example.com",
+		"To: ann@example.com",
+		"Subject: Forwarded",
+		"X-Campaign: spring",
+		"Content-Type: text/plain",
+		"",
+		"body",
+	}, "\r\n")
+
+	m, err := ParseEML(strings.NewReader(raw))
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"X-Campaign": "spring"}, m.Headers)
+	assert.Equal(t, []*Content{NewContent("text/plain", "body")}, m.Content)
+	assert.Nil(t, m.Validate())
+}
+
+func TestParseEML_unsupportedCharset(t *testing.T) {
+	// "こんにちは" in ISO-2022-JP and Shift_JIS
+	jis := "\x1b$B$3$s$K$A$O\x1b(B"
+	sjis := "\x82\xb1\x82\xf1\x82\xc9\x82\xbf\x82\xcd"
+	raw := strings.Join([]string{
+		"From: =?Shift_JIS?B?" + base64.StdEncoding.EncodeToString([]byte(sjis)) + "?= <shop@example.com>",
+		"To: ann@example.com",
+		"Subject: =?ISO-2022-JP?B?" + base64.StdEncoding.EncodeToString([]byte(jis)) + "?=",
+		"Content-Type: multipart/alternative; boundary=alt",
+		"",
+		"--alt",
+		"Content-Type: text/plain; charset=ISO-2022-JP",
+		"Content-Transfer-Encoding: 7bit",
+		"",
+		jis,
+		"--alt",
+		"Content-Type: text/html; charset=Shift_JIS",
+		"Content-Transfer-Encoding: base64",
+		"",
+		base64.StdEncoding.EncodeToString([]byte(sjis)),
+		"--alt",
+		"Content-Type: text/plain; charset=iso-8859-1",
+		"",
+		"Caf\xe9",
+		"--alt--",
+		"",
+	}, "\r\n")
+
+	m, err := ParseEML(strings.NewReader(raw))
+	if !assert.Nil(t, err) {
+		return
+	}
+	// the decoder lowercases the charset of encoded words it leaves encoded
+	assert.Equal(t, "=?iso-2022-jp?B?"+base64.StdEncoding.EncodeToString([]byte(jis))+"?=", m.Subject)
+	assert.Equal(t, NewEmail("shop@example.com", "=?shift_jis?B?"+base64.StdEncoding.EncodeToString([]byte(sjis))+"?="), m.From)
+	assert.Equal(t, []*Content{NewContent("text/plain", "Café")}, m.Content)
+	assert.Equal(t, []*Attachment{
+		{Content: base64.StdEncoding.EncodeToString([]byte(jis)), Type: "text/plain; charset=ISO-2022-JP", Filename: "attachment-1.txt", Disposition: "attachment"},
+		{Content: base64.StdEncoding.EncodeToString([]byte(sjis)), Type: "text/html; charset=Shift_JIS", Filename: "attachment-2.html", Disposition: "attachment"},
+	}, m.Attachments)
+}
+
+func TestParseEML_errors(t *testing.T) {
+	_, err := ParseEML(strings.NewReader("From: a@example.com\r\nContent-Transfer-Encoding: uuencode\r\n\r\nbody"))
+	assert.EqualError(t, err, `unsupported content-transfer-encoding "uuencode"`)
+
+	_, err = ParseEML(strings.NewReader("From: a@example.com\r\nX-Foo: =?utf-8?q?a=0D=0ABcc:_x@y?=\r\n\r\nbody"))
+	assert.EqualError(t, err, "header X-Foo: decoded value contains a line break")
+
+	_, err = ParseEML(strings.NewReader("From: not an address\r\n\r\nbody"))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,135 @@
+package sendgrid
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func emlTestMail() *InputSendMail {
+	m := NewInputSendMail()
+	m.SetFrom(NewEmail("from@example.com", "Shop"))
+	m.SetSubject("Order -order-")
+	m.ReplyTo = &ReplyTo{Email: "support@example.com"}
+	m.Headers = map[string]string{"X-Campaign": "spring"}
+	m.AddContent(NewContent("text/plain", "Hello -name-, your order -order- is ready."))
+	m.AddContent(NewContent("text/html", `<p>Hello -name-, <img src="cid:logo"></p>`))
+	m.AddAttachment(&Attachment{Content: base64.StdEncoding.EncodeToString([]byte("GIF89a")), Type: "image/gif", Filename: "logo.gif", Disposition: "inline", ContentID: "logo"})
+	m.AddAttachment(&Attachment{Content: base64.StdEncoding.EncodeToString([]byte("%PDF-1.4")), Filename: "invoice.pdf"})
+
+	p := NewPersonalization()
+	p.AddTo(NewEmail("ann@example.com", "Ann Müller"))
+	p.AddBcc(NewEmail("audit@example.com", ""))
+	p.Substitutions = map[string]string{"-name-": "Ann", "-order-": "#42"}
+	p.SetSendAt(time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC))
+	m.AddPersonalization(p)
+	return m
+}
+
+func TestInputSendMailEML(t *testing.T) {
+	b, err := emlTestMail().EML(0)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(string(b), "Date: Thu, 02 Jan 2025"))
+
+	msg, err := mail.ReadMessage(bytes.NewReader(b))
+	assert.Nil(t, err)
+
+	dec := new(mime.WordDecoder)
+	subject, _ := dec.DecodeHeader(msg.Header.Get("Subject"))
+	assert.Equal(t, "Order #42", subject)
+	assert.Equal(t, `"Shop" <from@example.com>`, msg.Header.Get("From"))
+	to, err := msg.Header.AddressList("To")
+	assert.Nil(t, err)
+	assert.Equal(t, []*mail.Address{{Name: "Ann Müller", Address: "ann@example.com"}}, to)
+	assert.Equal(t, "<audit@example.com>", msg.Header.Get("Bcc"))
+	assert.Equal(t, "<support@example.com>", msg.Header.Get("Reply-To"))
+	assert.Equal(t, "spring", msg.Header.Get("X-Campaign"))
+	assert.Equal(t, "1.0", msg.Header.Get("MIME-Version"))
+
+	mixed := readMultipart(t, msg.Header.Get("Content-Type"), msg.Body)
+	assert.Len(t, mixed, 2)
+	assert.Equal(t, `attachment; filename=invoice.pdf`, mixed[1].header.Get("Content-Disposition"))
+	assert.Equal(t, "application/pdf", mixed[1].header.Get("Content-Type"))
+	assert.Equal(t, "%PDF-1.4", mixed[1].decoded(t))
+
+	related := readMultipart(t, mixed[0].header.Get("Content-Type"), bytes.NewReader(mixed[0].body))
+	assert.Len(t, related, 2)
+	assert.Equal(t, "<logo>", related[1].header.Get("Content-ID"))
+	assert.Equal(t, "GIF89a", related[1].decoded(t))
+
+	alternative := readMultipart(t, related[0].header.Get("Content-Type"), bytes.NewReader(related[0].body))
+	assert.Len(t, alternative, 2)
+	assert.Equal(t, "text/plain; charset=utf-8", alternative[0].header.Get("Content-Type"))
+	assert.Equal(t, "Hello Ann, your order #42 is ready.", alternative[0].decoded(t))
+	assert.Equal(t, `<p>Hello Ann, <img src="cid:logo"></p>`, alternative[1].decoded(t))
+}
+
+func TestInputSendMailEML_singlePart(t *testing.T) {
+	m := emlTestMail()
+	m.Content = m.Content[:1]
+	m.Attachments = nil
+
+	b, err := m.EML(0)
+	assert.Nil(t, err)
+	msg, err := mail.ReadMessage(bytes.NewReader(b))
+	assert.Nil(t, err)
+	assert.Equal(t, "text/plain; charset=utf-8", msg.Header.Get("Content-Type"))
+	assert.Equal(t, "quoted-printable", msg.Header.Get("Content-Transfer-Encoding"))
+}
+
+func TestInputSendMailEML_errors(t *testing.T) {
+	m := emlTestMail()
+	_, err := m.EML(1)
+	assert.EqualError(t, err, "personalization 1 does not exist")
+
+	m.Content = nil
+	m.SetTemplateID("d-123")
+	_, err = m.EML(0)
+	assert.EqualError(t, err, "mail has no content to export")
+}
+
+type emlPart struct {
+	header mail.Header
+	body   []byte
+}
+
+func (p emlPart) decoded(t *testing.T) string {
+	t.Helper()
+	var r io.Reader = bytes.NewReader(p.body)
+	switch p.header.Get("Content-Transfer-Encoding") {
+	case "base64":
+		r = base64.NewDecoder(base64.StdEncoding, r)
+	case "quoted-printable":
+		r = quotedprintable.NewReader(r)
+	}
+	b, err := io.ReadAll(r)
+	assert.Nil(t, err)
+	return string(b)
+}
+
+func readMultipart(t *testing.T, contentType string, body io.Reader) []emlPart {
+	t.Helper()
+	_, params, err := mime.ParseMediaType(contentType)
+	assert.Nil(t, err)
+	var parts []emlPart
+	mr := multipart.NewReader(body, params["boundary"])
+	for {
+		p, err := mr.NextRawPart()
+		if err == io.EOF {
+			return parts
+		}
+		assert.Nil(t, err)
+		b, err := io.ReadAll(p)
+		assert.Nil(t, err)
+		parts = append(parts, emlPart{header: mail.Header(p.Header), body: b})
+	}
+}
@@ -0,0 +1,409 @@
+package sendgrid
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	// HeaderEventWebhookSignature is the header carrying the signature of a
+	// signed event webhook request.
+	HeaderEventWebhookSignature = "X-Twilio-Email-Event-Webhook-Signature"
+	// HeaderEventWebhookTimestamp is the header carrying the timestamp that
+	// is signed along with the request body.
+	HeaderEventWebhookTimestamp = "X-Twilio-Email-Event-Webhook-Timestamp"
+)
+
+// maxEventWebhookBody is the largest event batch that EventWebhookHandler
+// reads.
+const maxEventWebhookBody = 8 << 20
+
+// EventType is the type of an event posted by the Event Webhook.
+type EventType string
+
+const (
+	EventProcessed           EventType = "processed"
+	EventDeferred            EventType = "deferred"
+	EventDelivered           EventType = "delivered"
+	EventBounce              EventType = "bounce"
+	EventDropped             EventType = "dropped"
+	EventOpen                EventType = "open"
+	EventClick               EventType = "click"
+	EventSpamReport          EventType = "spamreport"
+	EventUnsubscribe         EventType = "unsubscribe"
+	EventGroupUnsubscribe    EventType = "group_unsubscribe"
+	EventGroupResubscribe    EventType = "group_resubscribe"
+	EventAccountStatusChange EventType = "account_status_change"
+)
+
+// Event is an event posted by the Event Webhook, one of *ProcessedEvent,
+// *DeferredEvent, *DeliveredEvent, *BounceEvent, *DroppedEvent, *OpenEvent,
+// *ClickEvent, *SpamReportEvent, *UnsubscribeEvent, *GroupUnsubscribeEvent,
+// *GroupResubscribeEvent, *AccountStatusChangeEvent, or *UnknownEvent for
+// other types.
+type Event interface {
+	Common() *EventCommon
+}
+
+// EventCommon holds the fields shared by all events.
+// see: https://www.twilio.com/docs/sendgrid/for-developers/tracking-events/event
+type EventCommon struct {
+	Event       EventType       `json:"event"`
+	Email       string          `json:"email,omitempty"`
+	Timestamp   int64           `json:"timestamp"`
+	SGEventID   string          `json:"sg_event_id"`
+	SGMessageID string          `json:"sg_message_id,omitempty"`
+	SMTPID      string          `json:"smtp-id,omitempty"`
+	Category    EventCategories `json:"category,omitempty"`
+	// CustomArgs holds the custom args of the mail, which SendGrid adds to
+	// the event as top-level string fields.
+	CustomArgs map[string]string `json:"-"`
+	// Raw is the JSON object of the event, for fields without a struct
+	// field.
+	Raw json.RawMessage `json:"-"`
+}
+
+// Common returns e.
+func (e *EventCommon) Common() *EventCommon {
+	return e
+}
+
+// EventCategories is the category field of an event, which SendGrid sends
+// as a string for a single category and as an array otherwise.
+type EventCategories []string
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (c *EventCategories) UnmarshalJSON(b []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte(`"`)) {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		*c = EventCategories{s}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(b, &list); err != nil {
+		return err
+	}
+	*c = list
+	return nil
+}
+
+// EventPool is the IP pool a mail was sent from.
+type EventPool struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// ProcessedEvent is posted when SendGrid accepts a mail for delivery.
+type ProcessedEvent struct {
+	EventCommon
+	Pool       *EventPool `json:"pool,omitempty"`
+	ASMGroupID int        `json:"asm_group_id,omitempty"`
+	SendAt     int64      `json:"send_at,omitempty"`
+}
+
+// DeferredEvent is posted when the receiving server temporarily rejects a
+// mail.
+type DeferredEvent struct {
+	EventCommon
+	Response string `json:"response,omitempty"`
+	Attempt  string `json:"attempt,omitempty"`
+	IP       string `json:"ip,omitempty"`
+}
+
+// DeliveredEvent is posted when the receiving server accepts a mail.
+type DeliveredEvent struct {
+	EventCommon
+	Response string `json:"response,omitempty"`
+	IP       string `json:"ip,omitempty"`
+}
+
+// BounceEvent is posted when the receiving server permanently rejects a
+// mail. Type is "bounce", or "blocked" for blocks.
+type BounceEvent struct {
+	EventCommon
+	Type                 string `json:"type,omitempty"`
+	Reason               string `json:"reason,omitempty"`
+	Status               string `json:"status,omitempty"`
+	BounceClassification string `json:"bounce_classification,omitempty"`
+	IP                   string `json:"ip,omitempty"`
+}
+
+// DroppedEvent is posted when SendGrid does not deliver a mail, for example
+// to a suppressed address.
+type DroppedEvent struct {
+	EventCommon
+	Reason string `json:"reason,omitempty"`
+	Status string `json:"status,omitempty"`
+}
+
+// OpenEvent is posted when a recipient opens a mail.
+type OpenEvent struct {
+	EventCommon
+	UserAgent     string `json:"useragent,omitempty"`
+	IP            string `json:"ip,omitempty"`
+	SGMachineOpen bool   `json:"sg_machine_open,omitempty"`
+}
+
+// ClickEvent is posted when a recipient clicks a tracked link.
+type ClickEvent struct {
+	EventCommon
+	URL       string          `json:"url,omitempty"`
+	URLOffset *EventURLOffset `json:"url_offset,omitempty"`
+	UserAgent string          `json:"useragent,omitempty"`
+	IP        string          `json:"ip,omitempty"`
+}
+
+// EventURLOffset is the position of the clicked link in the mail.
+type EventURLOffset struct {
+	Index int    `json:"index"`
+	Type  string `json:"type"`
+}
+
+// SpamReportEvent is posted when a recipient marks a mail as spam.
+type SpamReportEvent struct {
+	EventCommon
+}
+
+// UnsubscribeEvent is posted when a recipient unsubscribes from all mail.
+type UnsubscribeEvent struct {
+	EventCommon
+}
+
+// GroupUnsubscribeEvent is posted when a recipient unsubscribes from a
+// suppression group.
+type GroupUnsubscribeEvent struct {
+	EventCommon
+	ASMGroupID int    `json:"asm_group_id"`
+	UserAgent  string `json:"useragent,omitempty"`
+	IP         string `json:"ip,omitempty"`
+}
+
+// GroupResubscribeEvent is posted when a recipient resubscribes to a
+// suppression group.
+type GroupResubscribeEvent struct {
+	EventCommon
+	ASMGroupID int    `json:"asm_group_id"`
+	UserAgent  string `json:"useragent,omitempty"`
+	IP         string `json:"ip,omitempty"`
+}
+
+// AccountStatusChangeEvent is posted when the status of the account
+// changes, for example on a compliance suspension.
+type AccountStatusChangeEvent struct {
+	EventCommon
+	Type string `json:"type,omitempty"`
+}
+
+// UnknownEvent is an event of a type without a struct.
+type UnknownEvent struct {
+	EventCommon
+}
+
+// eventFields are the top-level fields of events that are not custom args.
+var eventFields = map[string]bool{
+	"event": true, "email": true, "timestamp": true, "sg_event_id": true,
+	"sg_message_id": true, "smtp-id": true, "category": true, "pool": true,
+	"asm_group_id": true, "send_at": true, "response": true, "attempt": true,
+	"ip": true, "tls": true, "cert_err": true, "type": true, "reason": true,
+	"status": true, "bounce_classification": true, "useragent": true,
+	"sg_machine_open": true, "url": true, "url_offset": true,
+	"sg_template_id": true, "sg_template_name": true, "sg_content_type": true,
+	"marketing_campaign_id": true, "marketing_campaign_name": true,
+	"marketing_campaign_version": true, "marketing_campaign_split_id": true,
+	"singlesend_id": true, "singlesend_name": true, "template_id": true,
+	"mc_stats": true, "phase_id": true, "post_type": true, "newsletter": true,
+}
+
+// ParseEvents decodes a batch of events posted by the Event Webhook.
+func ParseEvents(body []byte) ([]Event, error) {
+	var batch []json.RawMessage
+	if err := json.Unmarshal(body, &batch); err != nil {
+		return nil, fmt.Errorf("event batch: %w", err)
+	}
+	events := make([]Event, 0, len(batch))
+	for i, raw := range batch {
+		e, err := parseEvent(raw)
+		if err != nil {
+			return nil, fmt.Errorf("event %d: %w", i, err)
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+func parseEvent(raw json.RawMessage) (Event, error) {
+	var common EventCommon
+	if err := json.Unmarshal(raw, &common); err != nil {
+		return nil, err
+	}
+
+	var e Event
+	switch common.Event {
+	case EventProcessed:
+		e = new(ProcessedEvent)
+	case EventDeferred:
+		e = new(DeferredEvent)
+	case EventDelivered:
+		e = new(DeliveredEvent)
+	case EventBounce:
+		e = new(BounceEvent)
+	case EventDropped:
+		e = new(DroppedEvent)
+	case EventOpen:
+		e = new(OpenEvent)
+	case EventClick:
+		e = new(ClickEvent)
+	case EventSpamReport:
+		e = new(SpamReportEvent)
+	case EventUnsubscribe:
+		e = new(UnsubscribeEvent)
+	case EventGroupUnsubscribe:
+		e = new(GroupUnsubscribeEvent)
+	case EventGroupResubscribe:
+		e = new(GroupResubscribeEvent)
+	case EventAccountStatusChange:
+		e = new(AccountStatusChangeEvent)
+	default:
+		e = new(UnknownEvent)
+	}
+	if err := json.Unmarshal(raw, e); err != nil {
+		return nil, fmt.Errorf("%s: %w", common.Event, err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	c := e.Common()
+	c.Raw = raw
+	for k, v := range fields {
+		var s string
+		if eventFields[k] || json.Unmarshal(v, &s) != nil {
+			continue
+		}
+		if c.CustomArgs == nil {
+			c.CustomArgs = map[string]string{}
+		}
+		c.CustomArgs[k] = s
+	}
+	return e, nil
+}
+
+// EventWebhookHandler is an http.Handler that receives the event batches of
+// the Event Webhook and dispatches each event to the callbacks registered
+// with OnEvent, in order.
+//
+// If a callback fails, the handler responds with 500 and SendGrid posts the
+// batch again later, so callbacks should be idempotent, for example by
+// skipping events whose SGEventID was already handled. Register callbacks
+// before serving requests.
+type EventWebhookHandler struct {
+	publicKey *ecdsa.PublicKey
+	callbacks []func(context.Context, Event) error
+}
+
+// NewEventWebhookHandler returns an EventWebhookHandler. If publicKey is
+// not empty, requests must be signed with its private key; use the public
+// key returned by ToggleSignatureVerification or
+// GetSignedEventWebhooksPublicKey.
+func NewEventWebhookHandler(publicKey string) (*EventWebhookHandler, error) {
+	h := &EventWebhookHandler{}
+	if publicKey == "" {
+		return h, nil
+	}
+	der, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("event webhook public key: %w", err)
+	}
+	pub, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("event webhook public key: %w", err)
+	}
+	key, ok := pub.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.New("event webhook public key is not an ECDSA key")
+	}
+	h.publicKey = key
+	return h, nil
+}
+
+// OnEvent registers fn to be called with each event of type T. Use Event
+// for T to receive every event.
+func OnEvent[T Event](h *EventWebhookHandler, fn func(context.Context, T) error) {
+	h.callbacks = append(h.callbacks, func(ctx context.Context, e Event) error {
+		if t, ok := e.(T); ok {
+			return fn(ctx, t)
+		}
+		return nil
+	})
+}
+
+// ServeHTTP implements http.Handler.
+func (h *EventWebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxEventWebhookBody))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if h.publicKey != nil {
+		if err := h.verify(r.Header, body); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	}
+
+	events, err := ParseEvents(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, e := range events {
+		for _, fn := range h.callbacks {
+			if err := fn(r.Context(), e); err != nil {
+				http.Error(w, fmt.Sprintf("%s event %s: %v", e.Common().Event, e.Common().SGEventID, err), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// verify checks the ECDSA signature of the timestamp and body of a request.
+// see: https://www.twilio.com/docs/sendgrid/for-developers/tracking-events/getting-started-event-webhook-security-features
+func (h *EventWebhookHandler) verify(header http.Header, body []byte) error {
+	signature := header.Get(HeaderEventWebhookSignature)
+	timestamp := header.Get(HeaderEventWebhookTimestamp)
+	if signature == "" || timestamp == "" {
+		return errors.New("missing event webhook signature")
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(signature))
+	if err != nil {
+		return errors.New("malformed event webhook signature")
+	}
+	digest := sha256.New()
+	digest.Write([]byte(timestamp))
+	digest.Write(body)
+	if !ecdsa.VerifyASN1(h.publicKey, digest.Sum(nil), sig) {
+		return errors.New("invalid event webhook signature")
+	}
+	return nil
+}
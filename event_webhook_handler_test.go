@@ -0,0 +1,150 @@
+package sendgrid
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testEventBatch = `[
+	{"email":"a@example.com","timestamp":1700000000,"smtp-id":"<id@example.com>","event":"processed","category":"welcome","sg_event_id":"ev-1","sg_message_id":"msg-1.filter","pool":{"name":"transactional","id":210},"user":"alice"},
+	{"email":"a@example.com","timestamp":1700000001,"event":"deferred","category":["welcome","spring"],"sg_event_id":"ev-2","sg_message_id":"msg-1.filter","response":"400 try again later","attempt":"5"},
+	{"email":"a@example.com","timestamp":1700000002,"event":"delivered","sg_event_id":"ev-3","sg_message_id":"msg-1.filter","response":"250 OK","tls":1,"cert_err":0},
+	{"email":"b@example.com","timestamp":1700000003,"event":"bounce","sg_event_id":"ev-4","sg_message_id":"msg-2.filter","reason":"500 unknown recipient","status":"5.0.0","type":"bounce","bounce_classification":"Invalid Address"},
+	{"email":"b@example.com","timestamp":1700000004,"event":"dropped","sg_event_id":"ev-5","sg_message_id":"msg-2.filter","reason":"Bounced Address","status":"5.0.0"},
+	{"email":"a@example.com","timestamp":1700000005,"event":"open","sg_event_id":"ev-6","sg_message_id":"msg-1.filter","useragent":"Mozilla/4.0","ip":"255.255.255.255","sg_machine_open":true},
+	{"email":"a@example.com","timestamp":1700000006,"event":"click","sg_event_id":"ev-7","sg_message_id":"msg-1.filter","url":"https://example.com","url_offset":{"index":0,"type":"html"}},
+	{"email":"a@example.com","timestamp":1700000007,"event":"spamreport","sg_event_id":"ev-8","sg_message_id":"msg-1.filter"},
+	{"email":"a@example.com","timestamp":1700000008,"event":"unsubscribe","sg_event_id":"ev-9","sg_message_id":"msg-1.filter"},
+	{"email":"a@example.com","timestamp":1700000009,"event":"group_unsubscribe","sg_event_id":"ev-10","sg_message_id":"msg-1.filter","asm_group_id":42},
+	{"email":"a@example.com","timestamp":1700000010,"event":"group_resubscribe","sg_event_id":"ev-11","sg_message_id":"msg-1.filter","asm_group_id":42},
+	{"timestamp":1700000011,"event":"account_status_change","sg_event_id":"ev-12","type":"compliance_suspend"},
+	{"timestamp":1700000012,"event":"something_new","sg_event_id":"ev-13"}
+]`
+
+func TestParseEvents(t *testing.T) {
+	events, err := ParseEvents([]byte(testEventBatch))
+	if !assert.NoError(t, err) || !assert.Len(t, events, 13) {
+		return
+	}
+
+	processed, ok := events[0].(*ProcessedEvent)
+	if assert.True(t, ok) {
+		assert.Equal(t, "ev-1", processed.SGEventID)
+		assert.Equal(t, "msg-1.filter", processed.SGMessageID)
+		assert.Equal(t, EventCategories{"welcome"}, processed.Category)
+		assert.Equal(t, &EventPool{ID: 210, Name: "transactional"}, processed.Pool)
+		assert.Equal(t, map[string]string{"user": "alice"}, processed.CustomArgs)
+	}
+	deferred := events[1].(*DeferredEvent)
+	assert.Equal(t, EventCategories{"welcome", "spring"}, deferred.Category)
+	assert.Equal(t, "5", deferred.Attempt)
+	assert.Nil(t, events[2].Common().CustomArgs)
+	assert.Equal(t, "Invalid Address", events[3].(*BounceEvent).BounceClassification)
+	assert.Equal(t, "Bounced Address", events[4].(*DroppedEvent).Reason)
+	assert.True(t, events[5].(*OpenEvent).SGMachineOpen)
+	assert.Equal(t, &EventURLOffset{Index: 0, Type: "html"}, events[6].(*ClickEvent).URLOffset)
+	assert.IsType(t, &SpamReportEvent{}, events[7])
+	assert.IsType(t, &UnsubscribeEvent{}, events[8])
+	assert.Equal(t, 42, events[9].(*GroupUnsubscribeEvent).ASMGroupID)
+	assert.Equal(t, 42, events[10].(*GroupResubscribeEvent).ASMGroupID)
+	assert.Equal(t, "compliance_suspend", events[11].(*AccountStatusChangeEvent).Type)
+	unknown := events[12].(*UnknownEvent)
+	assert.Equal(t, EventType("something_new"), unknown.Event)
+	assert.JSONEq(t, `{"timestamp":1700000012,"event":"something_new","sg_event_id":"ev-13"}`, string(unknown.Raw))
+
+	_, err = ParseEvents([]byte(`{"event":"open"}`))
+	assert.Error(t, err)
+	_, err = ParseEvents([]byte(`[{"event":"open","timestamp":"now"}]`))
+	assert.ErrorContains(t, err, "event 0")
+}
+
+func TestEventWebhookHandler(t *testing.T) {
+	h, err := NewEventWebhookHandler("")
+	if !assert.NoError(t, err) {
+		return
+	}
+	var bounces []string
+	var all int
+	OnEvent(h, func(ctx context.Context, e *BounceEvent) error {
+		bounces = append(bounces, e.Email)
+		return nil
+	})
+	OnEvent(h, func(ctx context.Context, e Event) error {
+		all++
+		return nil
+	})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(testEventBatch)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{"b@example.com"}, bounces)
+	assert.Equal(t, 13, all)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/events", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/events", strings.NewReader("not json")))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	OnEvent(h, func(ctx context.Context, e *DroppedEvent) error {
+		return errors.New("database unavailable")
+	})
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(testEventBatch)))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, rec.Body.String(), "dropped event ev-5: database unavailable")
+}
+
+func TestEventWebhookHandler_Signature(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := NewEventWebhookHandler(base64.StdEncoding.EncodeToString(der))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	sign := func(timestamp, body string) string {
+		digest := sha256.Sum256([]byte(timestamp + body))
+		sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.StdEncoding.EncodeToString(sig)
+	}
+	serve := func(signature, timestamp, body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(body))
+		req.Header.Set(HeaderEventWebhookSignature, signature)
+		req.Header.Set(HeaderEventWebhookTimestamp, timestamp)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve(sign("1700000000", testEventBatch), "1700000000", testEventBatch))
+	assert.Equal(t, http.StatusForbidden, serve(sign("1700000000", testEventBatch), "1700000001", testEventBatch))
+	assert.Equal(t, http.StatusForbidden, serve("", "", testEventBatch))
+	assert.Equal(t, http.StatusForbidden, serve("!!!", "1700000000", testEventBatch))
+
+	_, err = NewEventWebhookHandler("not base64")
+	assert.Error(t, err)
+}
@@ -0,0 +1,697 @@
+package sendgrid
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RenderedTemplateVersion is a template version rendered locally by
+// RenderTemplateVersion.
+type RenderedTemplateVersion struct {
+	Subject      string
+	HTMLContent  string
+	PlainContent string
+	// Missing lists the variables the template prints that are absent from
+	// the data, in order of first use. Variables only tested by block
+	// helpers such as if and each are not reported.
+	Missing []string
+}
+
+// RenderTemplateVersion renders the subject, HTML and plain text content of
+// a dynamic template version with data, usually the DynamicTemplateData of a
+// Personalization, so that mail can be previewed before the version is
+// activated. If data is nil, the TestData of v is used.
+//
+// Rendering happens locally with the Handlebars dialect of SendGrid; see
+// RenderHandlebars for the supported syntax.
+func RenderTemplateVersion(v *OutputGetTemplateVersion, data map[string]interface{}) (*RenderedTemplateVersion, error) {
+	if data == nil && v.TestData != "" {
+		if err := json.Unmarshal([]byte(v.TestData), &data); err != nil {
+			return nil, fmt.Errorf("test_data: %w", err)
+		}
+	}
+
+	r := new(RenderedTemplateVersion)
+	seen := map[string]bool{}
+	for _, f := range []struct {
+		name string
+		src  string
+		dst  *string
+	}{
+		{"subject", v.Subject, &r.Subject},
+		{"html_content", v.HTMLContent, &r.HTMLContent},
+		{"plain_content", v.PlainContent, &r.PlainContent},
+	} {
+		out, missing, err := RenderHandlebars(f.src, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.name, err)
+		}
+		*f.dst = out
+		for _, m := range missing {
+			if !seen[m] {
+				seen[m] = true
+				r.Missing = append(r.Missing, m)
+			}
+		}
+	}
+	return r, nil
+}
+
+// RenderHandlebars renders src with data using the Handlebars dialect of
+// SendGrid dynamic templates. It returns the output and the variables that
+// are printed but absent from data, in order of first use.
+//
+// The supported syntax is {{var}}, {{{var}}}, paths such as user.name,
+// this, ../name and @index, @key, @first, @last and @root, comments,
+// ~ whitespace control, subexpressions, and the helpers if, unless, each,
+// with, equals, notEquals, greaterThan, lessThan, and, or, length,
+// formatDate and insert, including {{else if ...}} chains. data is encoded
+// to JSON first, so it is seen exactly as SendGrid sees it.
+func RenderHandlebars(src string, data map[string]interface{}) (string, []string, error) {
+	nodes, err := parseHandlebars(src)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var root interface{} = map[string]interface{}{}
+	if data != nil {
+		b, err := json.Marshal(data)
+		if err != nil {
+			return "", nil, err
+		}
+		if err := json.Unmarshal(b, &root); err != nil {
+			return "", nil, err
+		}
+	}
+
+	r := &hbRenderer{
+		root:  root,
+		stack: []hbFrame{{context: root}},
+		seen:  map[string]bool{},
+	}
+	if err := r.render(nodes); err != nil {
+		return "", nil, err
+	}
+	return r.out.String(), r.missing, nil
+}
+
+// hbFrame is a context pushed by each and with, and its @ variables.
+type hbFrame struct {
+	context interface{}
+	data    map[string]interface{}
+}
+
+type hbRenderer struct {
+	out     strings.Builder
+	root    interface{}
+	stack   []hbFrame
+	missing []string
+	seen    map[string]bool
+}
+
+func (r *hbRenderer) render(nodes []hbNode) error {
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case hbText:
+			r.out.WriteString(string(n))
+		case *hbMustache:
+			v, err := r.evalCall(n.call, true, n.line)
+			if err != nil {
+				return err
+			}
+			s := hbString(v)
+			if !n.raw {
+				s = hbEscape(s)
+			}
+			r.out.WriteString(s)
+		case *hbBlock:
+			if err := r.renderBlock(n); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *hbRenderer) push(context interface{}, data map[string]interface{}) {
+	r.stack = append(r.stack, hbFrame{context: context, data: data})
+}
+
+func (r *hbRenderer) pop() {
+	r.stack = r.stack[:len(r.stack)-1]
+}
+
+func (r *hbRenderer) renderBlock(b *hbBlock) error {
+	name := b.call.name.(*hbPath).original
+	params, err := r.evalParams(b.call.params, false, b.line)
+	if err != nil {
+		return err
+	}
+	arg := func(n int) error {
+		if len(params) != n {
+			return fmt.Errorf("line %d: {{#%s}} takes %d arguments, got %d", b.line, name, n, len(params))
+		}
+		return nil
+	}
+
+	var cond bool
+	switch name {
+	case "if", "unless":
+		if err := arg(1); err != nil {
+			return err
+		}
+		cond = hbTruthy(params[0]) == (name == "if")
+	case "with":
+		if err := arg(1); err != nil {
+			return err
+		}
+		if !hbTruthy(params[0]) {
+			return r.render(b.inverse)
+		}
+		r.push(params[0], nil)
+		defer r.pop()
+		return r.render(b.body)
+	case "each":
+		if err := arg(1); err != nil {
+			return err
+		}
+		return r.renderEach(b, params[0])
+	default:
+		if !hbConditions[name] {
+			return fmt.Errorf("line %d: unknown block helper {{#%s}}", b.line, name)
+		}
+		if cond, err = hbCondition(name, params); err != nil {
+			return fmt.Errorf("line %d: {{#%s}} %w", b.line, name, err)
+		}
+	}
+
+	if cond {
+		return r.render(b.body)
+	}
+	return r.render(b.inverse)
+}
+
+func (r *hbRenderer) renderEach(b *hbBlock, v interface{}) error {
+	switch v := v.(type) {
+	case []interface{}:
+		if len(v) == 0 {
+			break
+		}
+		for i, item := range v {
+			r.push(item, map[string]interface{}{
+				"index": float64(i),
+				"first": i == 0,
+				"last":  i == len(v)-1,
+			})
+			err := r.render(b.body)
+			r.pop()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]interface{}:
+		if len(v) == 0 {
+			break
+		}
+		// JSON objects are unordered once decoded; iterate in key order
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for i, k := range keys {
+			r.push(v[k], map[string]interface{}{
+				"key":   k,
+				"index": float64(i),
+				"first": i == 0,
+				"last":  i == len(keys)-1,
+			})
+			err := r.render(b.body)
+			r.pop()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return r.render(b.inverse)
+}
+
+// evalCall evaluates a mustache or subexpression. report records missing
+// variables whose value is printed.
+func (r *hbRenderer) evalCall(call *hbCall, report bool, line int) (interface{}, error) {
+	if p, ok := call.name.(*hbPath); ok && p.depth == 0 && !p.data && len(p.parts) == 1 {
+		switch name := p.parts[0]; {
+		case name == "formatDate" || name == "insert" || name == "length":
+			return r.callHelper(name, call.params, report, line)
+		case hbConditions[name]:
+			params, err := r.evalParams(call.params, false, line)
+			if err != nil {
+				return nil, err
+			}
+			cond, err := hbCondition(name, params)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s %w", line, name, err)
+			}
+			return cond, nil
+		}
+	}
+	if len(call.params) > 0 {
+		return nil, fmt.Errorf("line %d: unknown helper %s", line, hbValueString(call.name))
+	}
+	return r.evalValue(call.name, report, line)
+}
+
+func (r *hbRenderer) evalParams(params []hbValue, report bool, line int) ([]interface{}, error) {
+	values := make([]interface{}, len(params))
+	for i, p := range params {
+		v, err := r.evalValue(p, report, line)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func (r *hbRenderer) evalValue(v hbValue, report bool, line int) (interface{}, error) {
+	switch v := v.(type) {
+	case *hbLiteral:
+		return v.value, nil
+	case *hbCall:
+		return r.evalCall(v, report, line)
+	case *hbPath:
+		value, ok := r.lookup(v)
+		if !ok && report && !r.seen[v.original] {
+			r.seen[v.original] = true
+			r.missing = append(r.missing, v.original)
+		}
+		return value, nil
+	}
+	return nil, nil
+}
+
+// lookup resolves p against the context stack. It reports whether every
+// part of the path exists.
+func (r *hbRenderer) lookup(p *hbPath) (interface{}, bool) {
+	parts := p.parts
+	var v interface{}
+	switch {
+	case p.data && len(parts) > 0 && parts[0] == "root":
+		v, parts = r.root, parts[1:]
+	case p.data:
+		if len(parts) == 0 {
+			return nil, false
+		}
+		found := false
+		for i := len(r.stack) - 1 - p.depth; i >= 0; i-- {
+			if d, ok := r.stack[i].data[parts[0]]; ok {
+				v, found = d, true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+		parts = parts[1:]
+	default:
+		i := len(r.stack) - 1 - p.depth
+		if i < 0 {
+			return nil, false
+		}
+		v = r.stack[i].context
+	}
+
+	for _, part := range parts {
+		switch c := v.(type) {
+		case map[string]interface{}:
+			e, ok := c[part]
+			if !ok {
+				return nil, false
+			}
+			v = e
+		case []interface{}:
+			if part == "length" {
+				v = float64(len(c))
+				continue
+			}
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(c) {
+				return nil, false
+			}
+			v = c[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+func (r *hbRenderer) callHelper(name string, params []hbValue, report bool, line int) (interface{}, error) {
+	switch name {
+	case "insert":
+		// {{insert name "default=Customer"}}
+		if len(params) < 1 || len(params) > 2 {
+			return nil, fmt.Errorf("line %d: insert takes 1 or 2 arguments, got %d", line, len(params))
+		}
+		def, hasDefault := "", false
+		if len(params) == 2 {
+			var s string
+			if lit, ok := params[1].(*hbLiteral); ok {
+				s, _ = lit.value.(string)
+			}
+			if !strings.HasPrefix(s, "default=") {
+				return nil, fmt.Errorf(`line %d: the second argument of insert must be "default=..."`, line)
+			}
+			def, hasDefault = strings.TrimPrefix(s, "default="), true
+		}
+		v, err := r.evalValue(params[0], report && !hasDefault, line)
+		if err != nil {
+			return nil, err
+		}
+		if s := hbString(v); s != "" {
+			return s, nil
+		}
+		return def, nil
+	case "length":
+		if len(params) != 1 {
+			return nil, fmt.Errorf("line %d: length takes 1 argument, got %d", line, len(params))
+		}
+		v, err := r.evalValue(params[0], false, line)
+		if err != nil {
+			return nil, err
+		}
+		switch v := v.(type) {
+		case []interface{}:
+			return float64(len(v)), nil
+		case map[string]interface{}:
+			return float64(len(v)), nil
+		case string:
+			return float64(len([]rune(v))), nil
+		}
+		return float64(0), nil
+	case "formatDate":
+		// {{formatDate timeStamp dateFormat timezoneOffset}}
+		if len(params) < 2 || len(params) > 3 {
+			return nil, fmt.Errorf("line %d: formatDate takes 2 or 3 arguments, got %d", line, len(params))
+		}
+		values, err := r.evalParams(params, report, line)
+		if err != nil {
+			return nil, err
+		}
+		if values[0] == nil {
+			return "", nil
+		}
+		t, err := hbParseTime(values[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: formatDate: %w", line, err)
+		}
+		if len(values) == 3 && values[2] != nil {
+			loc, err := hbParseOffset(hbString(values[2]))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: formatDate: %w", line, err)
+			}
+			t = t.In(loc)
+		}
+		return hbFormatDate(t, hbString(values[1])), nil
+	}
+	return nil, nil
+}
+
+// hbConditions are the comparison helpers. They can be used as blocks, e.g.
+// {{#equals plan "pro"}}, or in subexpressions, e.g. {{#if (equals a b)}}.
+var hbConditions = map[string]bool{
+	"equals":      true,
+	"notEquals":   true,
+	"greaterThan": true,
+	"lessThan":    true,
+	"and":         true,
+	"or":          true,
+}
+
+func hbCondition(name string, params []interface{}) (bool, error) {
+	switch name {
+	case "and", "or":
+		if len(params) < 2 {
+			return false, fmt.Errorf("takes at least 2 arguments, got %d", len(params))
+		}
+		for _, p := range params {
+			if hbTruthy(p) != (name == "and") {
+				return name == "or", nil
+			}
+		}
+		return name == "and", nil
+	}
+
+	if len(params) != 2 {
+		return false, fmt.Errorf("takes 2 arguments, got %d", len(params))
+	}
+	switch name {
+	case "equals":
+		return hbEqual(params[0], params[1]), nil
+	case "notEquals":
+		return !hbEqual(params[0], params[1]), nil
+	}
+	a, okA := hbNumber(params[0])
+	b, okB := hbNumber(params[1])
+	if !okA || !okB {
+		return false, nil
+	}
+	if name == "greaterThan" {
+		return a > b, nil
+	}
+	return a < b, nil
+}
+
+// hbTruthy reports whether v is truthy for Handlebars: empty arrays are
+// falsy, like false, null, 0 and "".
+func hbTruthy(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case float64:
+		return v != 0 && !math.IsNaN(v)
+	case []interface{}:
+		return len(v) > 0
+	}
+	return true
+}
+
+func hbNumber(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// hbEqual compares numbers by value and anything else by its printed form,
+// so that {{#equals code 1}} matches both 1 and "1".
+func hbEqual(a, b interface{}) bool {
+	if x, ok := hbNumber(a); ok {
+		if y, ok := hbNumber(b); ok {
+			return x == y
+		}
+	}
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return hbString(a) == hbString(b)
+}
+
+// hbString formats v the way JavaScript converts values to strings.
+func hbString(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []interface{}:
+		s := make([]string, len(v))
+		for i, e := range v {
+			s[i] = hbString(e)
+		}
+		return strings.Join(s, ",")
+	case map[string]interface{}:
+		return "[object Object]"
+	}
+	return fmt.Sprint(v)
+}
+
+func hbValueString(v hbValue) string {
+	switch v := v.(type) {
+	case *hbPath:
+		return v.original
+	case *hbLiteral:
+		return hbString(v.value)
+	}
+	return "(...)"
+}
+
+var hbEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&quot;",
+	"'", "&#x27;",
+	"`", "&#x60;",
+	"=", "&#x3D;",
+)
+
+func hbEscape(s string) string {
+	return hbEscaper.Replace(s)
+}
+
+// hbParseTime parses an ISO 8601 timestamp or a number of milliseconds
+// since the Unix epoch.
+func hbParseTime(v interface{}) (time.Time, error) {
+	if ms, ok := v.(float64); ok {
+		return time.UnixMilli(int64(ms)).UTC(), nil
+	}
+	s := hbString(v)
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+// hbParseOffset parses a timezone offset such as -0800 or +05:30.
+func hbParseOffset(s string) (*time.Location, error) {
+	t, err := time.Parse("-0700", strings.ReplaceAll(s, ":", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone offset %q", s)
+	}
+	_, offset := t.Zone()
+	return time.FixedZone(s, offset), nil
+}
+
+// hbDateTokens are the tokens of formatDate formats, longest first.
+var hbDateTokens = []string{
+	"YYYY", "MMMM", "dddd", "SSS",
+	"MMM", "ddd", "YY", "MM", "DD", "Do", "HH", "hh", "mm", "ss", "ZZ",
+	"M", "D", "d", "H", "h", "m", "s", "A", "a", "Z", "X", "x",
+}
+
+// hbFormatDate formats t with a moment.js style format, e.g.
+// "MMMM DD, YYYY h:mm:ss A". Text in square brackets is copied verbatim.
+func hbFormatDate(t time.Time, format string) string {
+	var b strings.Builder
+	for format != "" {
+		if format[0] == '[' {
+			if end := strings.IndexByte(format, ']'); end > 0 {
+				b.WriteString(format[1:end])
+				format = format[end+1:]
+				continue
+			}
+		}
+		token := ""
+		for _, tok := range hbDateTokens {
+			if strings.HasPrefix(format, tok) {
+				token = tok
+				break
+			}
+		}
+		if token == "" {
+			b.WriteByte(format[0])
+			format = format[1:]
+			continue
+		}
+		format = format[len(token):]
+
+		hour12 := t.Hour() % 12
+		if hour12 == 0 {
+			hour12 = 12
+		}
+		switch token {
+		case "YYYY":
+			fmt.Fprintf(&b, "%04d", t.Year())
+		case "YY":
+			fmt.Fprintf(&b, "%02d", t.Year()%100)
+		case "MMMM":
+			b.WriteString(t.Month().String())
+		case "MMM":
+			b.WriteString(t.Month().String()[:3])
+		case "MM":
+			fmt.Fprintf(&b, "%02d", int(t.Month()))
+		case "M":
+			b.WriteString(strconv.Itoa(int(t.Month())))
+		case "DD":
+			fmt.Fprintf(&b, "%02d", t.Day())
+		case "D":
+			b.WriteString(strconv.Itoa(t.Day()))
+		case "Do":
+			b.WriteString(hbOrdinal(t.Day()))
+		case "dddd":
+			b.WriteString(t.Weekday().String())
+		case "ddd":
+			b.WriteString(t.Weekday().String()[:3])
+		case "d":
+			b.WriteString(strconv.Itoa(int(t.Weekday())))
+		case "HH":
+			fmt.Fprintf(&b, "%02d", t.Hour())
+		case "H":
+			b.WriteString(strconv.Itoa(t.Hour()))
+		case "hh":
+			fmt.Fprintf(&b, "%02d", hour12)
+		case "h":
+			b.WriteString(strconv.Itoa(hour12))
+		case "mm":
+			fmt.Fprintf(&b, "%02d", t.Minute())
+		case "m":
+			b.WriteString(strconv.Itoa(t.Minute()))
+		case "ss":
+			fmt.Fprintf(&b, "%02d", t.Second())
+		case "s":
+			b.WriteString(strconv.Itoa(t.Second()))
+		case "SSS":
+			fmt.Fprintf(&b, "%03d", t.Nanosecond()/int(time.Millisecond))
+		case "A":
+			b.WriteString(t.Format("PM"))
+		case "a":
+			b.WriteString(strings.ToLower(t.Format("PM")))
+		case "ZZ":
+			b.WriteString(t.Format("-0700"))
+		case "Z":
+			b.WriteString(t.Format("-07:00"))
+		case "X":
+			b.WriteString(strconv.FormatInt(t.Unix(), 10))
+		case "x":
+			b.WriteString(strconv.FormatInt(t.UnixMilli(), 10))
+		}
+	}
+	return b.String()
+}
+
+func hbOrdinal(n int) string {
+	suffix := "th"
+	if n%100 < 11 || n%100 > 13 {
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return strconv.Itoa(n) + suffix
+}
@@ -0,0 +1,544 @@
+package sendgrid
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// hbNode is a node of a parsed Handlebars template: hbText, *hbMustache or
+// *hbBlock.
+type hbNode interface{}
+
+type hbText string
+
+// hbMustache is an expression whose value is printed, {{expr}} or
+// {{{expr}}}.
+type hbMustache struct {
+	call *hbCall
+	raw  bool
+	line int
+}
+
+// hbBlock is a block helper call, {{#helper params}}body{{else}}inverse{{/helper}}.
+type hbBlock struct {
+	call    *hbCall
+	body    []hbNode
+	inverse []hbNode
+	line    int
+}
+
+// hbCall is a path or helper name followed by parameters.
+type hbCall struct {
+	name   hbValue
+	params []hbValue
+}
+
+// hbValue is a parameter: *hbPath, a literal or a subexpression (*hbCall).
+type hbValue interface{}
+
+// hbPath is a reference to the data, e.g. user.name, ../title or @index.
+type hbPath struct {
+	original string
+	depth    int
+	data     bool
+	parts    []string
+}
+
+// hbLiteral is a string, number, boolean or null literal.
+type hbLiteral struct {
+	value interface{}
+}
+
+type hbTagKind int
+
+const (
+	hbTagExpr hbTagKind = iota
+	hbTagRaw
+	hbTagOpen
+	hbTagClose
+	hbTagElse
+	hbTagComment
+)
+
+// hbToken is a piece of template source: text, or a tag between {{ and }}.
+type hbToken struct {
+	text string
+	tag  bool
+	kind hbTagKind
+	body string
+	line int
+
+	stripLeft, stripRight bool
+}
+
+// standalone reports whether a tag of this kind is removed together with
+// the whitespace around it when it is alone on a line.
+func (k hbTagKind) standalone() bool {
+	return k == hbTagOpen || k == hbTagClose || k == hbTagElse || k == hbTagComment
+}
+
+// parseHandlebars parses a template in the Handlebars dialect of SendGrid.
+func parseHandlebars(src string) ([]hbNode, error) {
+	tokens, err := lexHandlebars(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &hbParser{tokens: tokens}
+	nodes, end, err := p.parseProgram()
+	if err != nil {
+		return nil, err
+	}
+	if end != nil {
+		if end.kind == hbTagElse {
+			return nil, fmt.Errorf("line %d: {{else}} outside of a block", end.line)
+		}
+		return nil, fmt.Errorf("line %d: unexpected {{/%s}}", end.line, strings.TrimSpace(end.body))
+	}
+	return nodes, nil
+}
+
+func lexHandlebars(src string) ([]*hbToken, error) {
+	var tokens []*hbToken
+	line := 1
+	for len(src) > 0 {
+		i := strings.Index(src, "{{")
+		if i < 0 {
+			tokens = append(tokens, &hbToken{text: src, line: line})
+			break
+		}
+		if i > 0 {
+			tokens = append(tokens, &hbToken{text: src[:i], line: line})
+			line += strings.Count(src[:i], "\n")
+			src = src[i:]
+		}
+
+		t := &hbToken{tag: true, line: line}
+		var end int
+		switch {
+		case strings.HasPrefix(src, "{{!--") || strings.HasPrefix(src, "{{~!--"):
+			t.kind = hbTagComment
+			end = strings.Index(src, "--}}")
+			if e := strings.Index(src, "--~}}"); e >= 0 && (end < 0 || e < end) {
+				end = e + 1
+			}
+			if end >= 0 {
+				end += len("--")
+			}
+		case strings.HasPrefix(src, "{{{") || strings.HasPrefix(src, "{{~{"):
+			t.kind = hbTagRaw
+			end = strings.Index(src, "}}}")
+			if e := strings.Index(src, "}~}}"); e >= 0 && (end < 0 || e < end) {
+				end = e + 1
+			}
+			if end >= 0 {
+				end++
+			}
+		default:
+			end = strings.Index(src, "}}")
+		}
+		if end < 0 {
+			return nil, fmt.Errorf("line %d: unclosed tag", line)
+		}
+
+		inner := src[2:end]
+		if strings.HasPrefix(inner, "~") {
+			t.stripLeft = true
+			inner = inner[1:]
+		}
+		if strings.HasSuffix(inner, "~") {
+			t.stripRight = true
+			inner = inner[:len(inner)-1]
+		}
+		switch t.kind {
+		case hbTagComment:
+		case hbTagRaw:
+			t.body = strings.TrimSuffix(strings.TrimPrefix(inner, "{"), "}")
+		default:
+			t.kind, t.body = classifyTag(inner)
+		}
+		tokens = append(tokens, t)
+		line += strings.Count(src[:end+2], "\n")
+		src = src[end+2:]
+	}
+
+	stripWhitespace(tokens)
+	return tokens, nil
+}
+
+// classifyTag returns the kind of a tag and its content without the sigil.
+func classifyTag(inner string) (hbTagKind, string) {
+	s := strings.TrimSpace(inner)
+	switch {
+	case strings.HasPrefix(s, "!"):
+		return hbTagComment, ""
+	case strings.HasPrefix(s, "#"):
+		return hbTagOpen, s[1:]
+	case strings.HasPrefix(s, "/"):
+		return hbTagClose, s[1:]
+	case s == "^":
+		return hbTagElse, ""
+	case s == "else" || strings.HasPrefix(s, "else ") || strings.HasPrefix(s, "else\t") || strings.HasPrefix(s, "else\n"):
+		return hbTagElse, strings.TrimSpace(s[len("else"):])
+	}
+	return hbTagExpr, s
+}
+
+// stripWhitespace applies ~ whitespace control and removes the lines of
+// standalone block, else and comment tags, as Handlebars does.
+func stripWhitespace(tokens []*hbToken) {
+	// decide on the original text, before neighbouring tags trim it
+	standalone := make([]bool, len(tokens))
+	for i, t := range tokens {
+		if !t.tag || !t.kind.standalone() || t.stripLeft || t.stripRight {
+			continue
+		}
+		var prevOK, nextOK bool
+		switch {
+		case i == 0:
+			prevOK = true
+		case !tokens[i-1].tag:
+			prevOK = standalonePrefix(tokens[i-1].text, i == 1)
+		}
+		switch {
+		case i == len(tokens)-1:
+			nextOK = true
+		case !tokens[i+1].tag:
+			nextOK = standaloneSuffix(tokens[i+1].text, i+2 == len(tokens))
+		}
+		standalone[i] = prevOK && nextOK
+	}
+
+	for i, t := range tokens {
+		if !t.tag {
+			continue
+		}
+		var prev, next *hbToken
+		if i > 0 && !tokens[i-1].tag {
+			prev = tokens[i-1]
+		}
+		if i+1 < len(tokens) && !tokens[i+1].tag {
+			next = tokens[i+1]
+		}
+
+		if t.stripLeft && prev != nil {
+			prev.text = strings.TrimRight(prev.text, " \t\r\n")
+		}
+		if t.stripRight && next != nil {
+			next.text = strings.TrimLeft(next.text, " \t\r\n")
+		}
+		if !standalone[i] {
+			continue
+		}
+		if prev != nil {
+			prev.text = strings.TrimRight(prev.text, " \t")
+		}
+		if next != nil {
+			rest := strings.TrimLeft(next.text, " \t")
+			rest = strings.TrimPrefix(rest, "\r")
+			next.text = strings.TrimPrefix(rest, "\n")
+		}
+	}
+}
+
+func standalonePrefix(text string, first bool) bool {
+	trimmed := strings.TrimRight(text, " \t")
+	return strings.HasSuffix(trimmed, "\n") || (first && trimmed == "")
+}
+
+func standaloneSuffix(text string, last bool) bool {
+	trimmed := strings.TrimLeft(text, " \t")
+	return strings.HasPrefix(trimmed, "\n") || strings.HasPrefix(trimmed, "\r\n") || (last && trimmed == "")
+}
+
+type hbParser struct {
+	tokens []*hbToken
+	pos    int
+}
+
+// parseProgram parses nodes until an else or close tag, which is returned,
+// or the end of the template.
+func (p *hbParser) parseProgram() ([]hbNode, *hbToken, error) {
+	var nodes []hbNode
+	for p.pos < len(p.tokens) {
+		t := p.tokens[p.pos]
+		p.pos++
+		if !t.tag {
+			if t.text != "" {
+				nodes = append(nodes, hbText(t.text))
+			}
+			continue
+		}
+
+		switch t.kind {
+		case hbTagComment:
+		case hbTagExpr, hbTagRaw:
+			call, err := parseCall(t.body, t.line)
+			if err != nil {
+				return nil, nil, err
+			}
+			nodes = append(nodes, &hbMustache{call: call, raw: t.kind == hbTagRaw, line: t.line})
+		case hbTagOpen:
+			call, err := parseCall(t.body, t.line)
+			if err != nil {
+				return nil, nil, err
+			}
+			name, ok := blockHelperName(call)
+			if !ok {
+				return nil, nil, fmt.Errorf("line %d: invalid block helper %q", t.line, t.body)
+			}
+			b, err := p.parseBlock(call, name, t.line)
+			if err != nil {
+				return nil, nil, err
+			}
+			nodes = append(nodes, b)
+		case hbTagElse, hbTagClose:
+			return nodes, t, nil
+		}
+	}
+	return nodes, nil, nil
+}
+
+// parseBlock parses the body of a block opened by call up to its close tag,
+// which must match name. {{else helper ...}} chains are parsed as a nested
+// block in the inverse.
+func (p *hbParser) parseBlock(call *hbCall, name string, line int) (*hbBlock, error) {
+	b := &hbBlock{call: call, line: line}
+	body, end, err := p.parseProgram()
+	if err != nil {
+		return nil, err
+	}
+	b.body = body
+	if end == nil {
+		return nil, fmt.Errorf("line %d: unclosed block {{#%s}}", line, name)
+	}
+
+	if end.kind == hbTagElse {
+		if end.body != "" {
+			chained, err := parseCall(end.body, end.line)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := blockHelperName(chained); !ok {
+				return nil, fmt.Errorf("line %d: invalid block helper %q", end.line, end.body)
+			}
+			nested, err := p.parseBlock(chained, name, end.line)
+			if err != nil {
+				return nil, err
+			}
+			b.inverse = []hbNode{nested}
+			return b, nil
+		}
+		b.inverse, end, err = p.parseProgram()
+		if err != nil {
+			return nil, err
+		}
+		if end == nil {
+			return nil, fmt.Errorf("line %d: unclosed block {{#%s}}", line, name)
+		}
+		if end.kind == hbTagElse {
+			return nil, fmt.Errorf("line %d: duplicate {{else}} in block {{#%s}}", end.line, name)
+		}
+	}
+
+	if closing := strings.TrimSpace(end.body); closing != name {
+		return nil, fmt.Errorf("line %d: {{/%s}} does not match {{#%s}} on line %d", end.line, closing, name, line)
+	}
+	return b, nil
+}
+
+// blockHelperName returns the name of the block helper called by call, which
+// must be a simple name such as "if", not a literal, a subexpression, a ../
+// path or @data.
+func blockHelperName(call *hbCall) (string, bool) {
+	name, ok := call.name.(*hbPath)
+	if !ok || len(name.parts) != 1 || name.depth > 0 || name.data {
+		return "", false
+	}
+	return name.original, true
+}
+
+// parseCall parses the content of a tag: a name followed by parameters.
+func parseCall(s string, line int) (*hbCall, error) {
+	l := &hbExprLexer{src: s, line: line}
+	call, err := l.parseCall(false)
+	if err != nil {
+		return nil, err
+	}
+	if l.peek() != "" {
+		return nil, fmt.Errorf("line %d: unexpected %q in {{%s}}", line, l.peek(), s)
+	}
+	return call, nil
+}
+
+// hbExprLexer splits the content of a tag into words.
+type hbExprLexer struct {
+	src  string
+	pos  int
+	line int
+}
+
+func (l *hbExprLexer) skipSpace() {
+	for l.pos < len(l.src) && strings.ContainsRune(" \t\r\n", rune(l.src[l.pos])) {
+		l.pos++
+	}
+}
+
+// peek returns the next word without consuming it.
+func (l *hbExprLexer) peek() string {
+	pos := l.pos
+	w, _ := l.next()
+	l.pos = pos
+	return w
+}
+
+// next returns the next word: a parenthesis, a quoted string or a sequence
+// of other characters.
+func (l *hbExprLexer) next() (string, error) {
+	l.skipSpace()
+	if l.pos >= len(l.src) {
+		return "", nil
+	}
+	start := l.pos
+	switch c := l.src[l.pos]; c {
+	case '(', ')':
+		l.pos++
+	case '"', '\'':
+		l.pos++
+		for l.pos < len(l.src) && l.src[l.pos] != c {
+			if l.src[l.pos] == '\\' {
+				l.pos++
+			}
+			l.pos++
+		}
+		if l.pos >= len(l.src) {
+			return "", fmt.Errorf("line %d: unterminated string in {{%s}}", l.line, l.src)
+		}
+		l.pos++
+	default:
+		for l.pos < len(l.src) && !strings.ContainsRune(" \t\r\n()", rune(l.src[l.pos])) {
+			if l.src[l.pos] == '[' {
+				// segment literal, e.g. [first name]
+				if end := strings.IndexByte(l.src[l.pos:], ']'); end > 0 {
+					l.pos += end
+				}
+			}
+			l.pos++
+		}
+	}
+	return l.src[start:l.pos], nil
+}
+
+func (l *hbExprLexer) parseCall(sub bool) (*hbCall, error) {
+	name, err := l.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	if name == nil {
+		return nil, fmt.Errorf("line %d: empty expression", l.line)
+	}
+	call := &hbCall{name: name}
+	for {
+		w := l.peek()
+		if w == "" || (sub && w == ")") {
+			return call, nil
+		}
+		v, err := l.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		call.params = append(call.params, v)
+	}
+}
+
+func (l *hbExprLexer) parseValue() (hbValue, error) {
+	w, err := l.next()
+	if err != nil || w == "" {
+		return nil, err
+	}
+	switch {
+	case w == "(":
+		call, err := l.parseCall(true)
+		if err != nil {
+			return nil, err
+		}
+		if w, _ := l.next(); w != ")" {
+			return nil, fmt.Errorf("line %d: unclosed subexpression in {{%s}}", l.line, l.src)
+		}
+		return call, nil
+	case w == ")":
+		return nil, fmt.Errorf("line %d: unexpected ) in {{%s}}", l.line, l.src)
+	case w[0] == '"' || w[0] == '\'':
+		s := w[1 : len(w)-1]
+		s = strings.ReplaceAll(s, `\`+w[:1], w[:1])
+		return &hbLiteral{value: s}, nil
+	case w == "true" || w == "false":
+		return &hbLiteral{value: w == "true"}, nil
+	case w == "null" || w == "undefined":
+		return &hbLiteral{}, nil
+	case strings.Contains(w, "="):
+		return nil, fmt.Errorf("line %d: hash arguments are not supported: %s", l.line, w)
+	}
+	if f, err := strconv.ParseFloat(w, 64); err == nil {
+		return &hbLiteral{value: f}, nil
+	}
+	return parsePath(w, l.line)
+}
+
+// parsePath parses a path such as this, ./name, ../user.name, @index or
+// items.[0].
+func parsePath(s string, line int) (*hbPath, error) {
+	p := &hbPath{original: s}
+	rest := s
+	if strings.HasPrefix(rest, "@") {
+		p.data = true
+		rest = rest[1:]
+	}
+	for strings.HasPrefix(rest, "../") {
+		p.depth++
+		rest = rest[len("../"):]
+	}
+	rest = strings.TrimPrefix(rest, "./")
+	if rest == ".." {
+		p.depth++
+		rest = ""
+	}
+
+	if rest == "." {
+		rest = ""
+	}
+
+	for rest != "" {
+		var seg string
+		bracketed := strings.HasPrefix(rest, "[")
+		if bracketed {
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: invalid path %q", line, s)
+			}
+			seg, rest = rest[1:end], rest[end+1:]
+		} else if i := strings.IndexAny(rest, "./"); i >= 0 {
+			seg, rest = rest[:i], rest[i:]
+		} else {
+			seg, rest = rest, ""
+		}
+		if seg == "" && !bracketed {
+			return nil, fmt.Errorf("line %d: invalid path %q", line, s)
+		}
+		if rest != "" {
+			if rest[0] != '.' && rest[0] != '/' {
+				return nil, fmt.Errorf("line %d: invalid path %q", line, s)
+			}
+			rest = rest[1:]
+			if rest == "" {
+				return nil, fmt.Errorf("line %d: invalid path %q", line, s)
+			}
+		}
+		// this refers to the current context
+		if seg == "this" && !bracketed && len(p.parts) == 0 {
+			continue
+		}
+		p.parts = append(p.parts, seg)
+	}
+	return p, nil
+}
@@ -0,0 +1,128 @@
+package sendgrid
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderHandlebars(t *testing.T) {
+	data := map[string]interface{}{
+		"name":    "Ann <ann@example.com>",
+		"plan":    "PRO",
+		"balance": 12.5,
+		"user":    map[string]interface{}{"first": "Ann", "tags": []string{"a", "b"}},
+		"items": []map[string]interface{}{
+			{"title": "Book", "price": 10},
+			{"title": "Pen", "price": 2},
+		},
+		"prefs":   map[string]bool{"news": true, "offers": false},
+		"empty":   []string{},
+		"nothing": nil,
+	}
+
+	tests := []struct {
+		name    string
+		src     string
+		want    string
+		missing []string
+	}{
+		{"escape", "Hi {{name}}", "Hi Ann &lt;ann@example.com&gt;", nil},
+		{"raw", "Hi {{{name}}}", "Hi Ann <ann@example.com>", nil},
+		{"path", "{{user.first}} {{this.plan}} {{user.tags}} {{user.tags.[1]}} {{items.length}}", "Ann PRO a,b b 2", nil},
+		{"missing", "{{first_name}} {{user.last}} {{nothing}} {{first_name}}", "   ", []string{"first_name", "user.last"}},
+		{"comment", "a{{! note }}b{{!-- {{x}} --}}c", "abc", nil},
+		{"if", "{{#if user}}yes{{else}}no{{/if}} {{#if empty}}yes{{else}}no{{/if}} {{#if unknown}}yes{{/if}}", "yes no ", nil},
+		{"unless", "{{#unless nothing}}none{{/unless}}", "none", nil},
+		{"else if", "{{#if gold}}gold{{else if (equals plan \"PRO\")}}pro{{else}}free{{/if}}", "pro", nil},
+		{"equals", `{{#equals plan "PRO"}}pro{{else}}other{{/equals}}{{#equals balance "12.5"}}!{{/equals}}`, "pro!", nil},
+		{"notEquals", `{{#notEquals plan "PRO"}}other{{else}}pro{{/notEquals}}`, "pro", nil},
+		{"compare", "{{#greaterThan balance 10}}gt{{/greaterThan}}{{#lessThan balance 10}}lt{{else}}ge{{/lessThan}}", "gtge", nil},
+		{"and or", "{{#and plan balance}}and{{/and}}{{#or nothing empty}}or{{else}}none{{/or}}", "andnone", nil},
+		{"each", "{{#each items}}{{@index}}:{{title}}={{price}}{{#if @last}}.{{else}}, {{/if}}{{/each}}", "0:Book=10, 1:Pen=2.", nil},
+		{"each parent", "{{#each items}}{{../plan}}-{{@root.user.first}};{{/each}}", "PRO-Ann;PRO-Ann;", nil},
+		{"each object", "{{#each prefs}}{{@key}}={{this}} {{/each}}", "news=true offers=false ", nil},
+		{"each else", "{{#each empty}}x{{else}}no items{{/each}}", "no items", nil},
+		{"with", "{{#with user}}{{first}}{{/with}}{{#with unknown}}x{{else}}none{{/with}}", "Annnone", nil},
+		{"length", "{{length items}} {{#greaterThan (length items) 1}}many{{/greaterThan}}", "2 many", nil},
+		{"insert", `{{insert plan}} {{insert first_name "default=Customer"}}`, "PRO Customer", nil},
+		{"insert missing", `{{insert first_name}}`, "", []string{"first_name"}},
+		{"whitespace control", "a  {{~plan~}}  b", "aPROb", nil},
+		{"standalone", "<ul>\n  {{#each items}}\n  <li>{{title}}</li>\n  {{/each}}\n</ul>", "<ul>\n  <li>Book</li>\n  <li>Pen</li>\n</ul>", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, missing, err := RenderHandlebars(tt.src, data)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.missing, missing)
+		})
+	}
+}
+
+func TestRenderHandlebars_formatDate(t *testing.T) {
+	data := map[string]interface{}{
+		"timeStamp":      "2020-01-01T23:00:00.000Z",
+		"dateFormat":     "MMMM DD, YYYY h:mm:ss A",
+		"timezoneOffset": "-0800",
+	}
+
+	got, _, err := RenderHandlebars("{{formatDate timeStamp dateFormat}}|{{formatDate timeStamp dateFormat timezoneOffset}}|{{formatDate timeStamp \"ddd, Do MMM YY HH:mm Z\"}}", data)
+	assert.Nil(t, err)
+	assert.Equal(t, "January 01, 2020 11:00:00 PM|January 01, 2020 3:00:00 PM|Wed, 1st Jan 20 23:00 +00:00", got)
+
+	_, _, err = RenderHandlebars("{{formatDate dateFormat dateFormat}}", data)
+	assert.EqualError(t, err, `line 1: formatDate: invalid date "MMMM DD, YYYY h:mm:ss A"`)
+}
+
+func TestRenderHandlebars_errors(t *testing.T) {
+	tests := []struct {
+		src string
+		err string
+	}{
+		{"{{#if a}}x", "line 1: unclosed block {{#if}}"},
+		{"{{#if a}}\n{{/each}}", "line 2: {{/each}} does not match {{#if}} on line 1"},
+		{"x\n{{/if}}", "line 2: unexpected {{/if}}"},
+		{"{{else}}", "line 1: {{else}} outside of a block"},
+		{"{{#each a}}{{else}}{{else}}{{/each}}", "line 1: duplicate {{else}} in block {{#each}}"},
+		{"{{name", "line 1: unclosed tag"},
+		{"{{#loop a}}{{/loop}}", "line 1: unknown block helper {{#loop}}"},
+		{"{{upper name}}", "line 1: unknown helper upper"},
+		{"{{#if a b}}{{/if}}", "line 1: {{#if}} takes 1 arguments, got 2"},
+		{`{{insert name "fallback"}}`, `line 1: the second argument of insert must be "default=..."`},
+		{`{{link url target="_blank"}}`, `line 1: hash arguments are not supported: target="_blank"`},
+		{`{{#if a}}x{{else "foo"}}y{{/if}}`, `line 1: invalid block helper "\"foo\""`},
+		{"{{#if a}}x{{else (equals 1 1)}}y{{/if}}", `line 1: invalid block helper "(equals 1 1)"`},
+		{"{{#if a}}x\n{{else 3}}y{{/if}}", `line 2: invalid block helper "3"`},
+	}
+	for _, tt := range tests {
+		_, _, err := RenderHandlebars(tt.src, nil)
+		assert.EqualError(t, err, tt.err, tt.src)
+	}
+}
+
+func TestRenderTemplateVersion(t *testing.T) {
+	v := &OutputGetTemplateVersion{
+		Subject:      "Your order {{order.id}}",
+		HTMLContent:  "<p>Hello {{name}}, total {{order.total}}</p>",
+		PlainContent: "Hello {{name}}, {{coupon}}",
+		TestData:     `{"name":"Test","order":{"id":"T-1"}}`,
+	}
+
+	r, err := RenderTemplateVersion(v, map[string]interface{}{"name": "Ann", "order": map[string]interface{}{"id": 42}})
+	assert.Nil(t, err)
+	assert.Equal(t, &RenderedTemplateVersion{
+		Subject:      "Your order 42",
+		HTMLContent:  "<p>Hello Ann, total </p>",
+		PlainContent: "Hello Ann, ",
+		Missing:      []string{"order.total", "coupon"},
+	}, r)
+
+	r, err = RenderTemplateVersion(v, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "Your order T-1", r.Subject)
+
+	v.HTMLContent = "{{#if name}}"
+	_, err = RenderTemplateVersion(v, nil)
+	assert.EqualError(t, err, "html_content: line 1: unclosed block {{#if}}")
+}
@@ -0,0 +1,38 @@
+// Package redact removes secrets from JSON bodies. It is shared by the debug
+// dumps of the sendgrid package and the cassettes of sendgridtest, so that
+// both redact the same fields.
+package redact
+
+import "slices"
+
+// secretFields are the JSON object keys of request and response bodies whose
+// values are secrets.
+var secretFields = []string{
+	"password",
+	"api_key",
+	"oauth_client_secret",
+	"client_secret",
+	"authorization_token",
+	"signup_session_token",
+}
+
+// Secrets replaces the non-empty values of the secret fields in v, a value
+// decoded from JSON into interface{}, with replacement. Objects and arrays
+// are modified in place; the redacted value is returned.
+func Secrets(v interface{}, replacement string) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			if slices.Contains(secretFields, k) && e != nil && e != "" {
+				v[k] = replacement
+				continue
+			}
+			v[k] = Secrets(e, replacement)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = Secrets(e, replacement)
+		}
+	}
+	return v
+}
@@ -0,0 +1,20 @@
+package redact
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecrets(t *testing.T) {
+	v := map[string]interface{}{
+		"api_key": "SG.key",
+		"users":   []interface{}{map[string]interface{}{"username": "a", "password": "p"}},
+		"nested":  map[string]interface{}{"client_secret": nil},
+	}
+	assert.Equal(t, map[string]interface{}{
+		"api_key": "x",
+		"users":   []interface{}{map[string]interface{}{"username": "a", "password": "x"}},
+		"nested":  map[string]interface{}{"client_secret": nil},
+	}, Secrets(v, "x"))
+}
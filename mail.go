@@ -2,6 +2,7 @@ package sendgrid
 
 import (
 	"context"
+	"net/http"
 	"time"
 )
 
@@ -148,7 +149,11 @@ type ASM struct {
 
 // OutputSendMail represents the response from sending mail
 type OutputSendMail struct {
+	// MessageID is taken from the X-Message-Id response header. It matches the
+	// prefix of sg_message_id in Event Webhook payloads.
 	MessageID string `json:"message-id,omitempty"`
+	// Header holds the response headers.
+	Header http.Header `json:"-"`
 }
 
 // NewEmail creates a new Email struct
@@ -237,10 +242,14 @@ func (m *InputSendMail) AddCategory(category string) {
 	m.Categories = append(m.Categories, category)
 }
 
-// SendMail sends an email using SendGrid's mail/send API
+// SendMail sends an email using SendGrid's mail/send API. If the client has
+// OptionSandbox, the mail is sent in sandbox mode.
 // see: https://www.twilio.com/docs/sendgrid/api-reference/mail-send/mail-send
 func (c *Client) SendMail(ctx context.Context, input *InputSendMail) (*OutputSendMail, error) {
 	path := "/mail/send"
+	if c.sandbox {
+		input = withSandbox(input)
+	}
 
 	req, err := c.NewRequest("POST", path, input)
 	if err != nil {
@@ -248,9 +257,16 @@ func (c *Client) SendMail(ctx context.Context, input *InputSendMail) (*OutputSen
 	}
 
 	r := new(OutputSendMail)
-	if err := c.Do(ctx, req, &r); err != nil {
+	resp, err := c.doResponse(ctx, req, &r)
+	if err != nil {
 		return nil, err
 	}
 
+	// a middleware may answer without a response
+	if resp != nil {
+		r.Header = resp.Header
+		r.MessageID = resp.Header.Get("X-Message-Id")
+	}
+
 	return r, nil
 }
@@ -264,6 +264,32 @@ func TestSendMail(t *testing.T) {
 	assert.NotNil(t, result)
 }
 
+func TestSendMail_MessageID(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/mail/send", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		w.Header().Set("X-Message-Id", "W86EgYT6SQKk0lRflfLRsA")
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	mail := NewInputSendMail()
+	mail.SetFrom(NewEmail("from@example.com", "From User"))
+	mail.SetSubject("Test Email")
+
+	p := NewPersonalization()
+	p.AddTo(NewEmail("to@example.com", "To User"))
+	mail.AddPersonalization(p)
+
+	mail.AddContent(NewContent("text/plain", "Hello, World!"))
+
+	result, err := client.SendMail(context.TODO(), mail)
+	assert.NoError(t, err)
+	assert.Equal(t, "W86EgYT6SQKk0lRflfLRsA", result.MessageID)
+	assert.Equal(t, "W86EgYT6SQKk0lRflfLRsA", result.Header.Get("X-Message-Id"))
+}
+
 func TestSendMail_Failed(t *testing.T) {
 	client, mux, _, teardown := setup()
 	defer teardown()
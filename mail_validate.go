@@ -0,0 +1,190 @@
+package sendgrid
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Limits of the mail send API enforced by (*InputSendMail).Validate.
+// see: https://www.twilio.com/docs/sendgrid/api-reference/mail-send/limitations
+const (
+	MaxPersonalizations = 1000
+	MaxRecipients       = 1000
+	MaxScheduleAhead    = 72 * time.Hour
+	MaxMailSize         = 30 << 20
+)
+
+// Violation describes a field of InputSendMail that breaks a constraint of
+// the mail send API.
+type Violation struct {
+	Field   string
+	Message string
+}
+
+func (v Violation) String() string {
+	if v.Field == "" {
+		return v.Message
+	}
+	return v.Field + ": " + v.Message
+}
+
+// MailValidationError is returned by (*InputSendMail).Validate. It lists all
+// violations found.
+type MailValidationError struct {
+	Violations []Violation
+}
+
+func (e *MailValidationError) Error() string {
+	s := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		s[i] = v.String()
+	}
+	return "invalid mail: " + strings.Join(s, "; ")
+}
+
+// Validate checks m against the documented constraints of the mail send API,
+// so that mistakes are found before SendMail returns a 400 Bad Request. It
+// returns a *MailValidationError listing every violation, or nil.
+//
+// Validate checks that:
+//   - there are between 1 and MaxPersonalizations personalizations, each
+//     with at least one to address
+//   - there are at most MaxRecipients recipients in total
+//   - an address appears only once across to, cc and bcc of a personalization
+//   - text/plain content comes before text/html, which comes before any
+//     other type
+//   - ReplyTo and ReplyToList are not both set
+//   - SendAt is at most MaxScheduleAhead in the future
+//   - the encoded message is at most MaxMailSize bytes
+//   - without TemplateID, Content and a subject are set; with a dynamic
+//     template, personalizations do not use substitutions
+func (m *InputSendMail) Validate() error {
+	var violations []Violation
+	add := func(field, format string, args ...interface{}) {
+		violations = append(violations, Violation{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if m.From == nil || m.From.Email == "" {
+		add("from.email", "is required")
+	}
+
+	switch n := len(m.Personalizations); {
+	case n == 0:
+		add("personalizations", "at least one personalization is required")
+	case n > MaxPersonalizations:
+		add("personalizations", "%d personalizations exceed the limit of %d", n, MaxPersonalizations)
+	}
+
+	now := time.Now()
+	recipients := 0
+	for i, p := range m.Personalizations {
+		field := fmt.Sprintf("personalizations[%d]", i)
+		if p == nil {
+			add(field, "must not be null")
+			continue
+		}
+		if len(p.To) == 0 {
+			add(field+".to", "at least one recipient is required")
+		}
+		recipients += len(p.To) + len(p.Cc) + len(p.Bcc)
+
+		seen := map[string]string{}
+		for _, list := range []struct {
+			name   string
+			emails []*Email
+		}{{"to", p.To}, {"cc", p.Cc}, {"bcc", p.Bcc}} {
+			for j, e := range list.emails {
+				f := fmt.Sprintf("%s.%s[%d].email", field, list.name, j)
+				if e == nil || e.Email == "" {
+					add(f, "is required")
+					continue
+				}
+				key := strings.ToLower(e.Email)
+				if prev, ok := seen[key]; ok {
+					add(f, "%s is also in %s", e.Email, prev)
+					continue
+				}
+				seen[key] = fmt.Sprintf("%s.%s[%d]", field, list.name, j)
+			}
+		}
+
+		if p.SendAt != 0 && time.Unix(p.SendAt, 0).Sub(now) > MaxScheduleAhead {
+			add(field+".send_at", "cannot be scheduled more than %s ahead", MaxScheduleAhead)
+		}
+		if isDynamicTemplate(m.TemplateID) && len(p.Substitutions) > 0 {
+			add(field+".substitutions", "are not supported with dynamic templates; use dynamic_template_data")
+		}
+	}
+	if recipients > MaxRecipients {
+		add("personalizations", "%d recipients exceed the limit of %d", recipients, MaxRecipients)
+	}
+
+	rank := 0
+	for i, c := range m.Content {
+		field := fmt.Sprintf("content[%d]", i)
+		if c == nil {
+			add(field, "must not be null")
+			continue
+		}
+		if c.Value == "" {
+			add(field+".value", "is required")
+		}
+		r := contentRank(c.Type)
+		if r < rank {
+			add(field+".type", "%s must come before other content types; the order is text/plain, text/html, then others", c.Type)
+		}
+		rank = max(rank, r)
+	}
+
+	if m.ReplyTo != nil && len(m.ReplyToList) > 0 {
+		add("reply_to", "cannot be used together with reply_to_list")
+	}
+
+	if m.SendAt != 0 && time.Unix(m.SendAt, 0).Sub(now) > MaxScheduleAhead {
+		add("send_at", "cannot be scheduled more than %s ahead", MaxScheduleAhead)
+	}
+
+	if m.TemplateID == "" {
+		if len(m.Content) == 0 {
+			add("content", "is required unless template_id is set")
+		}
+		if m.Subject == "" {
+			for i, p := range m.Personalizations {
+				if p != nil && p.Subject == "" {
+					add(fmt.Sprintf("personalizations[%d].subject", i), "is required unless subject or template_id is set")
+				}
+			}
+		}
+	}
+
+	if b, err := json.Marshal(m); err != nil {
+		add("", "cannot be encoded: %v", err)
+	} else if len(b) > MaxMailSize {
+		add("", "the message size of %d bytes exceeds the limit of %d bytes", len(b), MaxMailSize)
+	}
+
+	if len(violations) > 0 {
+		return &MailValidationError{Violations: violations}
+	}
+	return nil
+}
+
+// isDynamicTemplate reports whether id is the ID of a dynamic template.
+func isDynamicTemplate(id string) bool {
+	return strings.HasPrefix(id, "d-")
+}
+
+// contentRank returns the position of a content type in the order required
+// by the mail send API.
+func contentRank(contentType string) int {
+	switch strings.ToLower(strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0])) {
+	case "text/plain":
+		return 0
+	case "text/html":
+		return 1
+	default:
+		return 2
+	}
+}
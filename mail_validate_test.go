@@ -0,0 +1,125 @@
+package sendgrid
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func validMail() *InputSendMail {
+	m := NewInputSendMail()
+	m.SetFrom(NewEmail("from@example.com", ""))
+	m.SetSubject("Hello")
+	p := NewPersonalization()
+	p.AddTo(NewEmail("to@example.com", ""))
+	m.AddPersonalization(p)
+	m.AddContent(NewContent("text/plain", "Hello"))
+	m.AddContent(NewContent("text/html", "<p>Hello</p>"))
+	return m
+}
+
+func violationFields(t *testing.T, err error) []string {
+	t.Helper()
+	var verr *MailValidationError
+	if !assert.ErrorAs(t, err, &verr) {
+		return nil
+	}
+	fields := make([]string, len(verr.Violations))
+	for i, v := range verr.Violations {
+		fields[i] = v.Field
+	}
+	return fields
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, validMail().Validate())
+
+	m := validMail()
+	m.SetTemplateID("d-12345")
+	m.Subject = ""
+	m.Content = nil
+	assert.NoError(t, m.Validate())
+}
+
+func TestValidateAllViolations(t *testing.T) {
+	m := &InputSendMail{
+		ReplyTo:     &ReplyTo{Email: "a@example.com"},
+		ReplyToList: []*ReplyToList{{Email: "b@example.com"}},
+		Content: []*Content{
+			NewContent("text/html", "<p>Hi</p>"),
+			NewContent("text/plain", "Hi"),
+		},
+		SendAt: time.Now().Add(73 * time.Hour).Unix(),
+	}
+
+	err := m.Validate()
+	assert.Equal(t, []string{"from.email", "personalizations", "content[1].type", "reply_to", "send_at"}, violationFields(t, err))
+	assert.Contains(t, err.Error(), "invalid mail: from.email: is required; personalizations: at least one personalization is required")
+}
+
+func TestValidatePersonalizations(t *testing.T) {
+	m := validMail()
+	p := &Personalization{
+		Cc:  []*Email{NewEmail("Dup@example.com", "")},
+		Bcc: []*Email{NewEmail("dup@example.com", ""), {}},
+	}
+	m.AddPersonalization(p)
+	m.Subject = ""
+	m.Personalizations[0].Subject = "Hi"
+
+	assert.Equal(t, []string{
+		"personalizations[1].to",
+		"personalizations[1].bcc[0].email",
+		"personalizations[1].bcc[1].email",
+		"personalizations[1].subject",
+	}, violationFields(t, m.Validate()))
+}
+
+func TestValidateLimits(t *testing.T) {
+	m := validMail()
+	for i := 0; i < MaxRecipients; i++ {
+		m.Personalizations[0].AddBcc(NewEmail(fmt.Sprintf("r%d@example.com", i), ""))
+	}
+	assert.Equal(t, []string{"personalizations"}, violationFields(t, m.Validate()))
+
+	m = validMail()
+	for i := 0; i < MaxPersonalizations; i++ {
+		p := NewPersonalization()
+		p.AddTo(NewEmail(fmt.Sprintf("r%d@example.com", i), ""))
+		m.AddPersonalization(p)
+	}
+	err := m.Validate()
+	assert.Equal(t, []string{"personalizations", "personalizations"}, violationFields(t, err))
+
+	m = validMail()
+	m.AddAttachment(&Attachment{Content: strings.Repeat("A", MaxMailSize), Filename: "big.bin"})
+	err = m.Validate()
+	assert.Equal(t, []string{""}, violationFields(t, err))
+	assert.Contains(t, err.Error(), "exceeds the limit")
+
+	// a message of exactly MaxMailSize bytes is accepted
+	m = validMail()
+	a := &Attachment{Filename: "big.bin"}
+	m.AddAttachment(a)
+	b, err := json.Marshal(m)
+	assert.Nil(t, err)
+	a.Content = strings.Repeat("A", MaxMailSize-len(b))
+	assert.Nil(t, m.Validate())
+	a.Content += "A"
+	assert.Equal(t, []string{""}, violationFields(t, m.Validate()))
+}
+
+func TestValidateTemplates(t *testing.T) {
+	m := validMail()
+	m.Content = nil
+	assert.Equal(t, []string{"content"}, violationFields(t, m.Validate()))
+
+	m = validMail()
+	m.SetTemplateID("d-12345")
+	m.Personalizations[0].Substitutions = map[string]string{"-name-": "Alice"}
+	assert.Equal(t, []string{"personalizations[0].substitutions"}, violationFields(t, m.Validate()))
+}
@@ -0,0 +1,90 @@
+package sendgrid
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+	"unicode"
+)
+
+// Handler performs a single attempt of an API call. The returned error is the
+// decoded API error, e.g. *APIError or *RateLimitedError. The response may be
+// nil if no response was received; its body is already consumed.
+type Handler func(ctx context.Context, req *http.Request) (*http.Response, error)
+
+// Middleware wraps a Handler to add cross-cutting behavior such as tracing,
+// metrics or header injection. Use OperationName to get the name of the
+// client method being called.
+type Middleware func(next Handler) Handler
+
+// OptionMiddleware appends middleware to the client. Middleware run in the
+// order they are given; the first one is the outermost. Each retry attempt
+// passes through the whole chain.
+func OptionMiddleware(mw ...Middleware) func(*Client) {
+	return func(c *Client) {
+		c.middleware = append(c.middleware, mw...)
+	}
+}
+
+type operationKey struct{}
+
+// WithOperationName returns a copy of ctx that names the operation passed to
+// middleware. Client methods set it automatically; use it when calling Do
+// directly.
+func WithOperationName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, operationKey{}, name)
+}
+
+// withDefaultOperationName names the operation of ctx unless the caller
+// already did. Client methods that make their requests through other client
+// methods use it, so that every request reports the method that was called.
+func withDefaultOperationName(ctx context.Context, name string) context.Context {
+	if OperationName(ctx) != "" {
+		return ctx
+	}
+	return WithOperationName(ctx, name)
+}
+
+// OperationName returns the name of the client method being called, such as
+// "SendMail" or "GetTemplates", or an empty string if it is unknown.
+func OperationName(ctx context.Context) string {
+	name, _ := ctx.Value(operationKey{}).(string)
+	return name
+}
+
+// handler returns the middleware chain around a single attempt that decodes
+// the response into v.
+func (c *Client) handler(v interface{}) Handler {
+	h := Handler(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		return c.do(ctx, req, v)
+	})
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		h = c.middleware[i](h)
+	}
+	return h
+}
+
+var clientMethodPrefix = reflect.TypeOf(Client{}).PkgPath() + ".(*Client)."
+
+// callerOperation returns the name of the innermost exported Client method,
+// other than Do, found on the call stack. Walking the stack is not free, so
+// it is only done when a middleware or slog logger can observe the name.
+func callerOperation() string {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		f, more := frames.Next()
+		if name, ok := strings.CutPrefix(f.Function, clientMethodPrefix); ok {
+			name, _, _ = strings.Cut(name, ".")
+			if name != "Do" && name != "" && unicode.IsUpper(rune(name[0])) {
+				return name
+			}
+		}
+		if !more {
+			return ""
+		}
+	}
+}
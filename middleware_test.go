@@ -0,0 +1,171 @@
+package sendgrid
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptionMiddleware(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/templates", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		assert.Equal(t, "outer,inner", r.Header.Get("X-Trace"))
+		fmt.Fprint(w, `{"result": [{"id": "d-12345abcde"}]}`)
+	})
+
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, req *http.Request) (*http.Response, error) {
+				if v := req.Header.Get("X-Trace"); v != "" {
+					req.Header.Set("X-Trace", v+","+name)
+				} else {
+					req.Header.Set("X-Trace", name)
+				}
+				start := time.Now()
+				resp, err := next(ctx, req)
+				calls = append(calls, fmt.Sprintf("%s:%s:%d:%v", name, OperationName(ctx), resp.StatusCode, err))
+				assert.Greater(t, time.Since(start), time.Duration(0))
+				return resp, err
+			}
+		}
+	}
+	OptionMiddleware(trace("outer"), trace("inner"))(client)
+
+	got, err := client.GetTemplates(context.TODO(), &InputGetTemplates{})
+	assert.NoError(t, err)
+	assert.Len(t, got.Templates, 1)
+	assert.Equal(t, []string{"inner:GetTemplates:200:<nil>", "outer:GetTemplates:200:<nil>"}, calls)
+}
+
+func TestOptionMiddlewareSeesDecodedError(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/mail/send", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"errors": [{"field": "from", "message": "invalid email"}]}`)
+	})
+
+	var (
+		operation string
+		apiErr    *APIError
+	)
+	OptionMiddleware(func(next Handler) Handler {
+		return func(ctx context.Context, req *http.Request) (*http.Response, error) {
+			resp, err := next(ctx, req)
+			operation = OperationName(ctx)
+			assert.ErrorAs(t, err, &apiErr)
+			return resp, err
+		}
+	})(client)
+
+	_, err := client.SendMail(context.TODO(), NewInputSendMail())
+	assert.Error(t, err)
+	assert.Equal(t, "SendMail", operation)
+	assert.Equal(t, "from", *apiErr.Errors[0].Field)
+}
+
+func TestOptionMiddlewareRetryRequest(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/templates/d-12345abcde", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer refreshed" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"id": "d-12345abcde"}`)
+	})
+
+	OptionMiddleware(func(next Handler) Handler {
+		return func(ctx context.Context, req *http.Request) (*http.Response, error) {
+			resp, err := next(ctx, req)
+			if IsUnauthorized(err) {
+				req.Header.Set("Authorization", "Bearer refreshed")
+				return next(ctx, req)
+			}
+			return resp, err
+		}
+	})(client)
+
+	got, err := client.GetTemplate(context.TODO(), "d-12345abcde")
+	assert.NoError(t, err)
+	assert.Equal(t, "d-12345abcde", got.ID)
+}
+
+func TestOperationNameFromDo(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/test", func(w http.ResponseWriter, r *http.Request) {})
+
+	var operations []string
+	OptionMiddleware(func(next Handler) Handler {
+		return func(ctx context.Context, req *http.Request) (*http.Response, error) {
+			operations = append(operations, OperationName(ctx))
+			return next(ctx, req)
+		}
+	})(client)
+
+	req, err := client.NewRequest("GET", "/test", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, client.Do(context.TODO(), req, nil))
+	assert.NoError(t, client.Do(WithOperationName(context.TODO(), "Custom"), req, nil))
+	assert.Equal(t, []string{"", "Custom"}, operations)
+}
+
+func TestOperationNameComposite(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/mail/batch", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"batch_id":"batch-1"}`)
+	})
+	mux.HandleFunc("/mail/send", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+	mux.HandleFunc("/designs", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"result":[]}`)
+	})
+
+	var operations []string
+	OptionMiddleware(func(next Handler) Handler {
+		return func(ctx context.Context, req *http.Request) (*http.Response, error) {
+			operations = append(operations, OperationName(ctx))
+			return next(ctx, req)
+		}
+	})(client)
+
+	_, err := client.ScheduleMail(context.TODO(), NewInputSendMail(), time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	_, err = client.GetDesigns(context.TODO())
+	assert.NoError(t, err)
+	_, err = client.CreateBatchID(WithOperationName(context.TODO(), "Custom"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ScheduleMail", "ScheduleMail", "GetDesigns", "Custom"}, operations)
+}
+
+func TestOptionMiddlewareNilResponse(t *testing.T) {
+	client, _, _, teardown := setup()
+	defer teardown()
+
+	OptionMiddleware(func(next Handler) Handler {
+		return func(ctx context.Context, req *http.Request) (*http.Response, error) {
+			return nil, nil
+		}
+	})(client)
+
+	out, err := client.SendMail(context.TODO(), NewInputSendMail())
+	assert.NoError(t, err)
+	assert.Empty(t, out.MessageID)
+	assert.Nil(t, out.Header)
+}
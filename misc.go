@@ -1,10 +1,13 @@
 package sendgrid
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httputil"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -56,7 +59,9 @@ func (t ErrorsResponse) Errs() error {
 			msg.WriteString(", ")
 		}
 		msg.WriteString("message: ")
-		msg.WriteString(*err.Message)
+		if err.Message != nil {
+			msg.WriteString(*err.Message)
+		}
 		s = append(s, msg.String())
 	}
 
@@ -67,26 +72,86 @@ func (t ErrorsResponse) Errs() error {
 	return errors.New(strings.Join(s, ", "))
 }
 
-// StatusCodeError represents an http response error.
-// type httpStatusCode interface { HTTPStatusCode() int } to handle it.
-type statusCodeError struct {
-	Code   int
+// APIError is returned when the SendGrid API responds with a non-2xx status
+// code other than 429. Use errors.As to inspect it.
+type APIError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// Status is the HTTP status line of the response, e.g. "404 Not Found".
 	Status string
+	// Method is the HTTP method of the request.
+	Method string
+	// Path is the URL path of the request.
+	Path string
+	// Errors holds per-field errors from {"errors": [...]} responses.
+	Errors []*Error
+	// Message holds the message from {"error": "..."} responses.
+	Message string
+	// Body is the raw response body.
+	Body []byte
+	// Header is the response header.
+	Header http.Header
+}
+
+func (e *APIError) Error() string {
+	var msg strings.Builder
+	msg.WriteString("sendgrid server error: ")
+	msg.WriteString(e.Status)
+	if err := (ErrorsResponse{Errors: e.Errors}).Errs(); err != nil {
+		msg.WriteString(": ")
+		msg.WriteString(err.Error())
+	} else if e.Message != "" {
+		msg.WriteString(": ")
+		msg.WriteString(e.Message)
+	}
+	return msg.String()
+}
+
+// HTTPStatusCode returns the HTTP status code of the response.
+func (e *APIError) HTTPStatusCode() int {
+	return e.StatusCode
+}
+
+// IsNotFound reports whether err is an *APIError with status 404.
+func IsNotFound(err error) bool {
+	return hasStatusCode(err, http.StatusNotFound)
+}
+
+// IsUnauthorized reports whether err is an *APIError with status 401.
+func IsUnauthorized(err error) bool {
+	return hasStatusCode(err, http.StatusUnauthorized)
 }
 
-func (t statusCodeError) Error() string {
-	return fmt.Sprintf("sendgrid server error: %s", t.Status)
+// IsForbidden reports whether err is an *APIError with status 403.
+func IsForbidden(err error) bool {
+	return hasStatusCode(err, http.StatusForbidden)
+}
+
+// IsRetryable reports whether err is a *RateLimitedError or an *APIError
+// whose status code indicates a temporary server side failure.
+func IsRetryable(err error) bool {
+	var rateLimited *RateLimitedError
+	if errors.As(err, &rateLimited) {
+		return true
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return slices.Contains(defaultRetryableStatusCodes, apiErr.StatusCode)
+	}
+	return false
 }
 
-func (t statusCodeError) HTTPStatusCode() int {
-	return t.Code
+func hasStatusCode(err error, code int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == code
 }
 
-func checkStatusCode(resp *http.Response, d debug) error {
+func checkStatusCode(resp *http.Response) error {
 	if resp.StatusCode == http.StatusTooManyRequests {
+		// without a usable reset time the wait is left to the retry policy
 		xRateLimitReset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
 		if err != nil {
-			return err
+			return &RateLimitedError{}
 		}
 
 		retryAfter := time.Until(time.Unix(xRateLimitReset, 0))
@@ -98,26 +163,35 @@ func checkStatusCode(resp *http.Response, d debug) error {
 		return nil
 	}
 
-	if err := logResponse(resp, d); err != nil {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
 		return err
 	}
 
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Body:       body,
+		Header:     resp.Header,
+	}
+	if resp.Request != nil {
+		apiErr.Method = resp.Request.Method
+		apiErr.Path = resp.Request.URL.Path
+	}
+
 	// {"errors": [{"field": "field name", "message": "error message"}]}
 	errorsResponse := new(ErrorsResponse)
-	if err := newJSONParser(errorsResponse)(resp); err == nil {
-		if errorsResponse.Errs() != nil {
-			return errorsResponse.Errs()
-		}
+	if err := json.Unmarshal(body, errorsResponse); err == nil {
+		apiErr.Errors = errorsResponse.Errors
 	}
 
 	// {"error": "error message"}
 	errorResponse := new(ErrorResponse)
-	if err := newJSONParser(errorResponse)(resp); err == nil {
-		if errorResponse.Err() != nil {
-			return errorResponse.Err()
-		}
+	if err := json.Unmarshal(body, errorResponse); err == nil {
+		apiErr.Message = errorResponse.Error
 	}
-	return statusCodeError{Code: resp.StatusCode, Status: resp.Status}
+
+	return apiErr
 }
 
 type responseParser func(*http.Response) error
@@ -128,14 +202,28 @@ func newJSONParser(dst interface{}) responseParser {
 	}
 }
 
-func logResponse(resp *http.Response, d debug) error {
-	if d.Debug() {
-		text, err := httputil.DumpResponse(resp, true)
-		if err != nil {
-			return err
-		}
-		d.Debugln(string(text))
+// logResponse dumps resp with secrets redacted. The body of resp is replaced
+// so that it can still be read.
+func logResponse(resp *http.Response, d debug, opts *DumpOptions) error {
+	if !d.Debug() {
+		return nil
 	}
 
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	r := *resp
+	b := redactDump(body, opts)
+	r.Body = io.NopCloser(bytes.NewReader(b))
+	r.ContentLength = int64(len(b))
+	text, err := httputil.DumpResponse(&r, true)
+	if err != nil {
+		return err
+	}
+	d.Debugln(string(text))
 	return nil
 }
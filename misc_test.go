@@ -12,6 +12,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -78,6 +79,12 @@ func TestStatusUnAuthorized(t *testing.T) {
 	if err == nil {
 		t.Fatal("expected an error but got none", err)
 	}
+
+	var apiErr *APIError
+	assert.ErrorAs(t, err, &apiErr)
+	assert.True(t, IsUnauthorized(err))
+	assert.Equal(t, "GET", apiErr.Method)
+	assert.Equal(t, "/v3/teammates/dummy", apiErr.Path)
 }
 
 func TestErrorResponseErr(t *testing.T) {
@@ -176,20 +183,55 @@ func TestErrorsResponseErrs(t *testing.T) {
 	}
 }
 
-func TestStatusCodeError(t *testing.T) {
-	err := statusCodeError{
-		Code:   500,
-		Status: "500 Internal Server Error",
+func TestAPIError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      *APIError
+		expected string
+	}{
+		{
+			name:     "status only",
+			err:      &APIError{StatusCode: 500, Status: "500 Internal Server Error"},
+			expected: "sendgrid server error: 500 Internal Server Error",
+		},
+		{
+			name: "errors",
+			err: &APIError{
+				StatusCode: 400,
+				Status:     "400 Bad Request",
+				Errors:     []*Error{{Field: String("email"), Message: String("invalid email")}},
+			},
+			expected: "sendgrid server error: 400 Bad Request: field: email, message: invalid email",
+		},
+		{
+			name:     "message",
+			err:      &APIError{StatusCode: 404, Status: "404 Not Found", Message: "not found"},
+			expected: "sendgrid server error: 404 Not Found: not found",
+		},
 	}
 
-	assert.Equal(t, "sendgrid server error: 500 Internal Server Error", err.Error())
-	assert.Equal(t, 500, err.HTTPStatusCode())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.err.Error())
+			assert.Equal(t, tt.err.StatusCode, tt.err.HTTPStatusCode())
+		})
+	}
 }
 
-func TestCheckStatusCode(t *testing.T) {
-	// Mock debug interface
-	debug := &mockDebug{debug: true}
+func TestAPIErrorHelpers(t *testing.T) {
+	notFound := errors.Wrap(&APIError{StatusCode: http.StatusNotFound}, "get teammate")
+	assert.True(t, IsNotFound(notFound))
+	assert.False(t, IsUnauthorized(notFound))
+	assert.False(t, IsRetryable(notFound))
+
+	assert.True(t, IsUnauthorized(&APIError{StatusCode: http.StatusUnauthorized}))
+	assert.True(t, IsForbidden(&APIError{StatusCode: http.StatusForbidden}))
+	assert.True(t, IsRetryable(&APIError{StatusCode: http.StatusServiceUnavailable}))
+	assert.True(t, IsRetryable(&RateLimitedError{RetryAfter: time.Second}))
+	assert.False(t, IsNotFound(errors.New("not found")))
+}
 
+func TestCheckStatusCode(t *testing.T) {
 	tests := []struct {
 		name              string
 		statusCode        int
@@ -221,7 +263,13 @@ func TestCheckStatusCode(t *testing.T) {
 			name:              "rate limit with invalid header",
 			statusCode:        429,
 			headers:           map[string]string{"X-RateLimit-Reset": "invalid"},
-			expectedError:     "invalid syntax",
+			expectedError:     "sendgrid rate limit exceeded, retry after 0s",
+			shouldReturnError: true,
+		},
+		{
+			name:              "rate limit without header",
+			statusCode:        429,
+			expectedError:     "sendgrid rate limit exceeded, retry after 0s",
 			shouldReturnError: true,
 		},
 		{
@@ -255,11 +303,14 @@ func TestCheckStatusCode(t *testing.T) {
 				resp.Header.Set(k, v)
 			}
 
-			err := checkStatusCode(resp, debug)
+			err := checkStatusCode(resp)
 
 			if tt.shouldReturnError {
 				assert.Error(t, err)
 				assert.Contains(t, err.Error(), tt.expectedError)
+				if tt.statusCode == http.StatusTooManyRequests {
+					assert.True(t, IsRetryable(err))
+				}
 			} else {
 				assert.NoError(t, err)
 			}
@@ -268,9 +319,6 @@ func TestCheckStatusCode(t *testing.T) {
 }
 
 func TestCheckStatusCodeErrorResponse(t *testing.T) {
-	// Mock debug interface
-	debug := &mockDebug{debug: true}
-
 	// Test single error response parsing
 	resp := &http.Response{
 		StatusCode: 404,
@@ -279,12 +327,13 @@ func TestCheckStatusCodeErrorResponse(t *testing.T) {
 		Body:       io.NopCloser(strings.NewReader(`{"error": "not found"}`)),
 	}
 
-	err := checkStatusCode(resp, debug)
+	err := checkStatusCode(resp)
 
-	assert.Error(t, err)
-	// Due to how the function works, first it tries to parse as ErrorsResponse which fails
-	// Then it tries ErrorResponse which also fails because body is already consumed
-	// So it falls back to statusCodeError
+	var apiErr *APIError
+	assert.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	assert.Equal(t, "not found", apiErr.Message)
+	assert.Equal(t, []byte(`{"error": "not found"}`), apiErr.Body)
 	assert.Contains(t, err.Error(), "sendgrid server error: 404 Not Found")
 }
 
@@ -317,7 +366,7 @@ func TestLogResponse(t *testing.T) {
 				Body:       &mockReadCloser{strings.NewReader("test body")},
 			}
 
-			err := logResponse(resp, debug)
+			err := logResponse(resp, debug, nil)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -339,7 +388,7 @@ func TestLogResponseError(t *testing.T) {
 		Body:       &errorReadCloser{},
 	}
 
-	err := logResponse(resp, debug)
+	err := logResponse(resp, debug, nil)
 	assert.Error(t, err)
 }
 
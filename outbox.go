@@ -0,0 +1,474 @@
+package sendgrid
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// OutboxKeyArg is the custom arg that carries the idempotency key of mail
+// sent through an Outbox. It appears in Event Webhook payloads, so that
+// consumers can detect mail delivered twice.
+const OutboxKeyArg = "outbox_key"
+
+// OutboxStatus is the state of an OutboxEntry.
+type OutboxStatus string
+
+const (
+	// OutboxPending entries wait to be sent or retried.
+	OutboxPending OutboxStatus = "pending"
+	// OutboxSent entries were accepted by SendGrid.
+	OutboxSent OutboxStatus = "sent"
+	// OutboxFailed entries were rejected, or failed on every attempt.
+	OutboxFailed OutboxStatus = "failed"
+)
+
+// OutboxEntry describes a mail stored in an Outbox.
+type OutboxEntry struct {
+	Key         string
+	Status      OutboxStatus
+	Attempts    int
+	MessageID   string
+	LastError   string
+	NextAttempt time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+
+	mail json.RawMessage
+}
+
+// outboxRecord is a line of the outbox file. The first record of an entry
+// holds the mail; later records update its state.
+type outboxRecord struct {
+	Key         string          `json:"key"`
+	Mail        json.RawMessage `json:"mail,omitempty"`
+	Status      OutboxStatus    `json:"status"`
+	Attempts    int             `json:"attempts,omitempty"`
+	MessageID   string          `json:"message_id,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	NextAttempt int64           `json:"next_attempt,omitempty"`
+	Created     int64           `json:"created,omitempty"`
+	Time        int64           `json:"time"`
+}
+
+// Outbox persists mail to an append-only file before it is sent with
+// SendMail, so that mail survives a crash of the process. Every state change
+// is synced to disk before the call that made it returns.
+//
+//	outbox, err := sendgrid.OpenOutbox(client, "/var/lib/app/outbox.jsonl", nil)
+//	key, err := outbox.Enqueue(mail)
+//	err = outbox.Drain(ctx)
+//
+// Mail is deduplicated by the idempotency key stored in the OutboxKeyArg
+// custom arg. If the process dies after SendGrid accepted a mail but before
+// the outbox recorded it, the mail is sent again when the outbox is drained
+// after the restart; the key lets recipients of the Event Webhook detect
+// such duplicates.
+//
+// An outbox file must not be opened by more than one process at a time.
+type Outbox struct {
+	client *Client
+	retry  *RetryPolicy
+	path   string
+	now    func() time.Time
+
+	mu       sync.Mutex
+	file     *os.File
+	entries  map[string]*OutboxEntry
+	order    []string
+	inflight map[string]bool
+}
+
+// OpenOutbox opens the outbox stored in the file at path, creating it if
+// needed, and restores its entries. Entries that were pending when the
+// outbox was last used are sent by the next Drain.
+//
+// retry sets the number of attempts per mail, the backoff between them and
+// the status codes that are retried; a nil policy means DefaultRetryPolicy.
+// Rate limited sends, transport errors and API errors with a status code in
+// RetryableStatusCodes are retried; other API errors fail the entry
+// immediately. The outbox sends with a copy of c without its OptionRetry
+// policy, so each attempt is a single request.
+func OpenOutbox(c *Client, path string, retry *RetryPolicy) (*Outbox, error) {
+	if retry == nil {
+		retry = DefaultRetryPolicy()
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	// the outbox schedules its own retries; retrying inside SendMail too
+	// would multiply the attempts
+	client := *c
+	client.retry = nil
+	o := &Outbox{
+		client:   &client,
+		retry:    retry,
+		path:     path,
+		now:      time.Now,
+		file:     f,
+		entries:  map[string]*OutboxEntry{},
+		inflight: map[string]bool{},
+	}
+	if err := o.load(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return o, nil
+}
+
+// load replays the records of the file. A truncated last record, left by a
+// crash during a write, is discarded.
+func (o *Outbox) load() error {
+	r := bufio.NewReader(o.file)
+	var offset int64
+	for line := 1; ; line++ {
+		b, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			if len(bytes.TrimSpace(b)) > 0 {
+				// incomplete write; drop it so that new records start on a
+				// fresh line
+				if err := o.file.Truncate(offset); err != nil {
+					return err
+				}
+			}
+			break
+		}
+		if err != nil {
+			return err
+		}
+		offset += int64(len(b))
+		if len(bytes.TrimSpace(b)) == 0 {
+			continue
+		}
+
+		var rec outboxRecord
+		if err := json.Unmarshal(b, &rec); err != nil {
+			return fmt.Errorf("%s:%d: %w", o.path, line, err)
+		}
+		o.apply(&rec)
+	}
+	return nil
+}
+
+func (o *Outbox) apply(rec *outboxRecord) {
+	e, ok := o.entries[rec.Key]
+	if !ok {
+		e = &OutboxEntry{Key: rec.Key, CreatedAt: time.UnixMilli(rec.Created)}
+		o.entries[rec.Key] = e
+		o.order = append(o.order, rec.Key)
+	}
+	if rec.Mail != nil {
+		e.mail = rec.Mail
+	}
+	e.Status = rec.Status
+	e.Attempts = rec.Attempts
+	e.MessageID = rec.MessageID
+	e.LastError = rec.Error
+	e.NextAttempt = time.Time{}
+	if rec.NextAttempt != 0 {
+		e.NextAttempt = time.UnixMilli(rec.NextAttempt)
+	}
+	e.UpdatedAt = time.UnixMilli(rec.Time)
+}
+
+// record appends the state of e to the file and syncs it. It is called with
+// o.mu held.
+func (o *Outbox) record(e *OutboxEntry, mail json.RawMessage) error {
+	rec := &outboxRecord{
+		Key:       e.Key,
+		Mail:      mail,
+		Status:    e.Status,
+		Attempts:  e.Attempts,
+		MessageID: e.MessageID,
+		Error:     e.LastError,
+		Created:   e.CreatedAt.UnixMilli(),
+		Time:      e.UpdatedAt.UnixMilli(),
+	}
+	if !e.NextAttempt.IsZero() {
+		rec.NextAttempt = e.NextAttempt.UnixMilli()
+	}
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := o.file.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	return o.file.Sync()
+}
+
+// Enqueue stores m in the outbox and returns its idempotency key. The key is
+// taken from the OutboxKeyArg custom arg of m, or generated and added to it.
+// If an entry with the same key exists, m is not stored again.
+func (o *Outbox) Enqueue(m *InputSendMail) (string, error) {
+	key := m.CustomArgs[OutboxKeyArg]
+	if key == "" {
+		var b [16]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return "", err
+		}
+		key = hex.EncodeToString(b[:])
+		if m.CustomArgs == nil {
+			m.CustomArgs = map[string]string{}
+		}
+		m.CustomArgs[OutboxKeyArg] = key
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.file == nil {
+		return "", errors.New("outbox is closed")
+	}
+	if _, ok := o.entries[key]; ok {
+		return key, nil
+	}
+
+	mail, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	now := o.now()
+	e := &OutboxEntry{Key: key, Status: OutboxPending, CreatedAt: now, UpdatedAt: now}
+	if err := o.record(e, mail); err != nil {
+		return "", err
+	}
+	e.mail = mail
+	o.entries[key] = e
+	o.order = append(o.order, key)
+	return key, nil
+}
+
+// Entry returns the entry with the given key.
+func (o *Outbox) Entry(key string) (OutboxEntry, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	e, ok := o.entries[key]
+	if !ok {
+		return OutboxEntry{}, false
+	}
+	return *e, true
+}
+
+// Entries returns all entries in the order they were enqueued.
+func (o *Outbox) Entries() []OutboxEntry {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	entries := make([]OutboxEntry, len(o.order))
+	for i, key := range o.order {
+		entries[i] = *o.entries[key]
+	}
+	return entries
+}
+
+// Drain sends the pending entries and retries failed attempts with backoff
+// until no entry is pending, or ctx is done. Entries that fail permanently
+// are marked OutboxFailed and do not stop the others; Drain returns an error
+// only if ctx is done or the outbox file cannot be written.
+func (o *Outbox) Drain(ctx context.Context) error {
+	for {
+		due, wait, pending := o.due()
+		if !pending {
+			return nil
+		}
+		if len(due) == 0 {
+			if err := sleepContext(ctx, wait); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, e := range due {
+			err := o.send(ctx, e)
+			o.mu.Lock()
+			delete(o.inflight, e.Key)
+			o.mu.Unlock()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// due returns the pending entries whose next attempt is due, marking them in
+// flight, and the time until the next one is due. pending reports whether any
+// entry is pending.
+func (o *Outbox) due() (due []*OutboxEntry, wait time.Duration, pending bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	now := o.now()
+	for _, key := range o.order {
+		e := o.entries[key]
+		if e.Status != OutboxPending {
+			continue
+		}
+		pending = true
+		if o.inflight[key] {
+			// sent by a concurrent Drain; check again shortly
+			if wait == 0 || wait > o.retry.MinBackoff {
+				wait = max(o.retry.MinBackoff, 10*time.Millisecond)
+			}
+			continue
+		}
+		if d := e.NextAttempt.Sub(now); d > 0 {
+			if wait == 0 || d < wait {
+				wait = d
+			}
+			continue
+		}
+		o.inflight[key] = true
+		due = append(due, e)
+	}
+	return due, wait, pending
+}
+
+// send makes an attempt to send e and records the outcome.
+func (o *Outbox) send(ctx context.Context, e *OutboxEntry) error {
+	m := new(InputSendMail)
+	mailErr := json.Unmarshal(e.mail, m)
+
+	var (
+		out *OutputSendMail
+		err = mailErr
+	)
+	if err == nil {
+		out, err = o.client.SendMail(ctx, m)
+		if err != nil && ctx.Err() != nil {
+			// the attempt was interrupted; leave the entry pending
+			return ctx.Err()
+		}
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.file == nil {
+		return errors.New("outbox is closed")
+	}
+	next := *e
+	next.Attempts++
+	next.UpdatedAt = o.now()
+	next.NextAttempt = time.Time{}
+	switch {
+	case err == nil:
+		next.Status = OutboxSent
+		next.MessageID = out.MessageID
+		next.LastError = ""
+	case mailErr != nil || !o.retryable(err) || next.Attempts >= o.retry.maxAttempts():
+		next.Status = OutboxFailed
+		next.LastError = err.Error()
+	default:
+		next.LastError = err.Error()
+		next.NextAttempt = next.UpdatedAt.Add(o.retry.backoff(next.Attempts, err))
+	}
+	if err := o.record(&next, nil); err != nil {
+		return err
+	}
+	*e = next
+	return nil
+}
+
+// retryable reports whether a failed send may succeed later. It agrees with
+// IsRetryable on API errors, using the status codes of the retry policy.
+// Transport errors are retried, since the mail may not have reached
+// SendGrid.
+func (o *Outbox) retryable(err error) bool {
+	var rateLimited *RateLimitedError
+	if errors.As(err, &rateLimited) {
+		return true
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return o.retry.retryableStatus(apiErr.StatusCode)
+	}
+	return true
+}
+
+// Compact rewrites the outbox file with a single record per entry, dropping
+// the history of state changes. Entries that are no longer pending are kept
+// so that their keys are still deduplicated; Remove discards them.
+func (o *Outbox) Compact() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.compact()
+}
+
+func (o *Outbox) compact() error {
+	if o.file == nil {
+		return errors.New("outbox is closed")
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(o.path), filepath.Base(o.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	compacted := &Outbox{file: tmp}
+	for _, key := range o.order {
+		e := o.entries[key]
+		if err := compacted.record(e, e.mail); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), o.path); err != nil {
+		return err
+	}
+	// make the rename durable
+	if dir, err := os.Open(filepath.Dir(o.path)); err == nil {
+		_ = dir.Sync()
+		dir.Close()
+	}
+
+	f, err := os.OpenFile(o.path, os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	o.file.Close()
+	o.file = f
+	return nil
+}
+
+// Remove forgets the entries that are no longer pending and were last
+// updated before the given time, and compacts the file. Their keys are no longer
+// deduplicated.
+func (o *Outbox) Remove(before time.Time) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	order := o.order[:0]
+	for _, key := range o.order {
+		e := o.entries[key]
+		if e.Status != OutboxPending && e.UpdatedAt.Before(before) {
+			delete(o.entries, key)
+			continue
+		}
+		order = append(order, key)
+	}
+	o.order = order
+	return o.compact()
+}
+
+// Close closes the outbox file.
+func (o *Outbox) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.file == nil {
+		return nil
+	}
+	err := o.file.Close()
+	o.file = nil
+	return err
+}
@@ -0,0 +1,236 @@
+package sendgrid
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var outboxTestRetry = &RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+
+func TestOutbox(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	var calls atomic.Int32
+	mux.HandleFunc("/mail/send", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPost)
+		var m InputSendMail
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&m))
+		w.Header().Set("X-Message-Id", "msg-"+m.CustomArgs[OutboxKeyArg])
+		calls.Add(1)
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	path := filepath.Join(t.TempDir(), "outbox.jsonl")
+	outbox, err := OpenOutbox(client, path, outboxTestRetry)
+	assert.Nil(t, err)
+
+	m := validMail()
+	key, err := outbox.Enqueue(m)
+	assert.Nil(t, err)
+	assert.Equal(t, key, m.CustomArgs[OutboxKeyArg])
+
+	m2 := validMail()
+	m2.CustomArgs = map[string]string{OutboxKeyArg: "order-42"}
+	key2, err := outbox.Enqueue(m2)
+	assert.Nil(t, err)
+	assert.Equal(t, "order-42", key2)
+
+	// a duplicate is not stored again
+	_, err = outbox.Enqueue(m2)
+	assert.Nil(t, err)
+	assert.Len(t, outbox.Entries(), 2)
+
+	assert.Nil(t, outbox.Drain(context.Background()))
+	assert.Equal(t, int32(2), calls.Load())
+	e, ok := outbox.Entry("order-42")
+	assert.True(t, ok)
+	assert.Equal(t, OutboxSent, e.Status)
+	assert.Equal(t, "msg-order-42", e.MessageID)
+	assert.Equal(t, 1, e.Attempts)
+	assert.Nil(t, outbox.Close())
+
+	// the state survives a restart, and sent mail is still deduplicated
+	outbox, err = OpenOutbox(client, path, outboxTestRetry)
+	assert.Nil(t, err)
+	defer outbox.Close()
+	entries := outbox.Entries()
+	assert.Len(t, entries, 2)
+	assert.Equal(t, key, entries[0].Key)
+	assert.Equal(t, OutboxSent, entries[0].Status)
+	assert.Equal(t, "msg-"+key, entries[0].MessageID)
+	_, err = outbox.Enqueue(m2)
+	assert.Nil(t, err)
+	assert.Nil(t, outbox.Drain(context.Background()))
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestOutbox_retry(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	var calls atomic.Int32
+	mux.HandleFunc("/mail/send", func(w http.ResponseWriter, r *http.Request) {
+		var m InputSendMail
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&m))
+		n := calls.Add(1)
+		switch m.Personalizations[0].To[0].Email {
+		case "flaky@example.com":
+			if n == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		case "down@example.com":
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		case "internal@example.com":
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		case "invalid@example.com":
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"errors":[{"field":"from","message":"invalid"}]}`)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	// client retries are not multiplied by the attempts of the outbox
+	OptionRetry(&RetryPolicy{MaxAttempts: 4, RetryableMethods: []string{http.MethodPost}})(client)
+
+	outbox, err := OpenOutbox(client, filepath.Join(t.TempDir(), "outbox.jsonl"), outboxTestRetry)
+	assert.Nil(t, err)
+	defer outbox.Close()
+
+	enqueue := func(to string) string {
+		m := validMail()
+		m.Personalizations[0].To[0].Email = to
+		key, err := outbox.Enqueue(m)
+		assert.Nil(t, err)
+		return key
+	}
+	flaky := enqueue("flaky@example.com")
+	down := enqueue("down@example.com")
+	internal := enqueue("internal@example.com")
+	invalid := enqueue("invalid@example.com")
+	assert.Nil(t, outbox.Drain(context.Background()))
+
+	e, _ := outbox.Entry(flaky)
+	assert.Equal(t, OutboxSent, e.Status)
+	assert.Equal(t, 2, e.Attempts)
+	assert.Empty(t, e.LastError)
+
+	e, _ = outbox.Entry(down)
+	assert.Equal(t, OutboxFailed, e.Status)
+	assert.Equal(t, 3, e.Attempts)
+	assert.Contains(t, e.LastError, "502")
+
+	// 500 is not in the retryable status codes, as with IsRetryable
+	e, _ = outbox.Entry(internal)
+	assert.Equal(t, OutboxFailed, e.Status)
+	assert.Equal(t, 1, e.Attempts)
+	assert.Contains(t, e.LastError, "500")
+
+	e, _ = outbox.Entry(invalid)
+	assert.Equal(t, OutboxFailed, e.Status)
+	assert.Equal(t, 1, e.Attempts)
+	assert.Contains(t, e.LastError, "400")
+
+	assert.Equal(t, int32(7), calls.Load())
+}
+
+func TestOutbox_crash(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/mail/send", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Message-Id", "msg-1")
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	path := filepath.Join(t.TempDir(), "outbox.jsonl")
+	outbox, err := OpenOutbox(client, path, outboxTestRetry)
+	assert.Nil(t, err)
+	key, err := outbox.Enqueue(validMail())
+	assert.Nil(t, err)
+	assert.Nil(t, outbox.Close())
+
+	// simulate a crash in the middle of writing a record
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	assert.Nil(t, err)
+	_, err = f.WriteString(`{"key":"` + key + `","status":"se`)
+	assert.Nil(t, err)
+	assert.Nil(t, f.Close())
+
+	outbox, err = OpenOutbox(client, path, outboxTestRetry)
+	assert.Nil(t, err)
+	defer outbox.Close()
+	e, _ := outbox.Entry(key)
+	assert.Equal(t, OutboxPending, e.Status)
+
+	assert.Nil(t, outbox.Drain(context.Background()))
+	e, _ = outbox.Entry(key)
+	assert.Equal(t, OutboxSent, e.Status)
+
+	b, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	assert.Len(t, lines, 2)
+	for _, line := range lines {
+		assert.True(t, json.Valid([]byte(line)), line)
+	}
+}
+
+func TestOutbox_corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.jsonl")
+	assert.Nil(t, os.WriteFile(path, []byte("not json\n"), 0o600))
+
+	_, err := OpenOutbox(New("key"), path, nil)
+	assert.ErrorContains(t, err, "outbox.jsonl:1:")
+}
+
+func TestOutbox_Remove(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/mail/send", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	path := filepath.Join(t.TempDir(), "outbox.jsonl")
+	outbox, err := OpenOutbox(client, path, outboxTestRetry)
+	assert.Nil(t, err)
+	defer outbox.Close()
+
+	sent, _ := outbox.Enqueue(validMail())
+	assert.Nil(t, outbox.Drain(context.Background()))
+	pending, _ := outbox.Enqueue(validMail())
+
+	assert.Nil(t, outbox.Compact())
+	assert.Len(t, outbox.Entries(), 2)
+
+	assert.Nil(t, outbox.Remove(time.Now().Add(time.Second)))
+	entries := outbox.Entries()
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, pending, entries[0].Key)
+	}
+	_, ok := outbox.Entry(sent)
+	assert.False(t, ok)
+
+	// the compacted file restores the same state and accepts new records
+	_, err = outbox.Enqueue(validMail())
+	assert.Nil(t, err)
+	reopened, err := OpenOutbox(client, path, outboxTestRetry)
+	assert.Nil(t, err)
+	defer reopened.Close()
+	assert.Len(t, reopened.Entries(), 2)
+}
@@ -0,0 +1,268 @@
+package sendgrid
+
+import (
+	"context"
+	"iter"
+	"net/url"
+)
+
+// defaultPageSize is used by the All* iterators when the input does not
+// specify a page size. The iterators need a known page size to detect the
+// last page of offset based endpoints.
+const defaultPageSize = 100
+
+// paginate returns an iterator over the items of the pages returned by fetch.
+// fetch receives the cursor of the page to fetch and returns its items, the
+// cursor of the next page and whether there are more pages. Pages are
+// fetched lazily; iteration stops at the first error, which is yielded with
+// the zero value of T.
+func paginate[T, C any](ctx context.Context, cursor C, fetch func(C) ([]T, C, bool, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		next := cursor
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(zero, err)
+				return
+			}
+
+			items, c, more, err := fetch(next)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if !more || len(items) == 0 {
+				return
+			}
+			next = c
+		}
+	}
+}
+
+// offsetPages returns an iterator for offset/limit endpoints. fetch receives
+// the limit and offset of the page to fetch.
+func offsetPages[T any](ctx context.Context, limit, offset int, fetch func(limit, offset int) ([]T, error)) iter.Seq2[T, error] {
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	return paginate(ctx, offset, func(offset int) ([]T, int, bool, error) {
+		items, err := fetch(limit, offset)
+		return items, offset + len(items), len(items) >= limit, err
+	})
+}
+
+// pageToken extracts the page_token query parameter from the next URL of
+// paged responses.
+func pageToken(next string) string {
+	u, err := url.Parse(next)
+	if err != nil {
+		return ""
+	}
+	return u.Query().Get("page_token")
+}
+
+// AllBounces returns an iterator over all bounces matching opts.
+// Limit and Offset of opts set the page size and the starting position.
+func (c *Client) AllBounces(ctx context.Context, opts *SuppressionListOptions) iter.Seq2[Bounce, error] {
+	o := SuppressionListOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	return offsetPages(ctx, o.Limit, o.Offset, func(limit, offset int) ([]Bounce, error) {
+		page := o
+		page.Limit, page.Offset = limit, offset
+		return c.GetBounces(ctx, &page)
+	})
+}
+
+// AllBlocks returns an iterator over all blocks matching opts.
+// Limit and Offset of opts set the page size and the starting position.
+func (c *Client) AllBlocks(ctx context.Context, opts *SuppressionListOptions) iter.Seq2[Block, error] {
+	o := SuppressionListOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	return offsetPages(ctx, o.Limit, o.Offset, func(limit, offset int) ([]Block, error) {
+		page := o
+		page.Limit, page.Offset = limit, offset
+		return c.GetBlocks(ctx, &page)
+	})
+}
+
+// AllSpamReports returns an iterator over all spam reports matching opts.
+// Limit and Offset of opts set the page size and the starting position.
+func (c *Client) AllSpamReports(ctx context.Context, opts *SuppressionListOptions) iter.Seq2[SpamReport, error] {
+	o := SuppressionListOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	return offsetPages(ctx, o.Limit, o.Offset, func(limit, offset int) ([]SpamReport, error) {
+		page := o
+		page.Limit, page.Offset = limit, offset
+		return c.GetSpamReports(ctx, &page)
+	})
+}
+
+// AllInvalidEmails returns an iterator over all invalid emails matching opts.
+// Limit and Offset of opts set the page size and the starting position.
+func (c *Client) AllInvalidEmails(ctx context.Context, opts *SuppressionListOptions) iter.Seq2[InvalidEmail, error] {
+	o := SuppressionListOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	return offsetPages(ctx, o.Limit, o.Offset, func(limit, offset int) ([]InvalidEmail, error) {
+		page := o
+		page.Limit, page.Offset = limit, offset
+		return c.GetInvalidEmails(ctx, &page)
+	})
+}
+
+// AllSubusers returns an iterator over all subusers matching input.
+func (c *Client) AllSubusers(ctx context.Context, input *InputGetSubusers) iter.Seq2[*Subuser, error] {
+	in := InputGetSubusers{}
+	if input != nil {
+		in = *input
+	}
+	return offsetPages(ctx, in.Limit, in.Offset, func(limit, offset int) ([]*Subuser, error) {
+		page := in
+		page.Limit, page.Offset = limit, offset
+		return c.GetSubusers(ctx, &page)
+	})
+}
+
+// AllAuthenticatedDomains returns an iterator over all authenticated domains
+// matching input.
+func (c *Client) AllAuthenticatedDomains(ctx context.Context, input *InputGetAuthenticatedDomains) iter.Seq2[*DomainAuthentication, error] {
+	in := InputGetAuthenticatedDomains{}
+	if input != nil {
+		in = *input
+	}
+	return offsetPages(ctx, in.Limit, in.Offset, func(limit, offset int) ([]*DomainAuthentication, error) {
+		page := in
+		page.Limit, page.Offset = limit, offset
+		return c.GetAuthenticatedDomains(ctx, &page)
+	})
+}
+
+// AllReverseDNSs returns an iterator over all reverse DNS records matching
+// input.
+func (c *Client) AllReverseDNSs(ctx context.Context, input *InputGetReverseDNSs) iter.Seq2[*OutputGetReverseDNS, error] {
+	in := InputGetReverseDNSs{}
+	if input != nil {
+		in = *input
+	}
+	return offsetPages(ctx, in.Limit, in.Offset, func(limit, offset int) ([]*OutputGetReverseDNS, error) {
+		page := in
+		page.Limit, page.Offset = limit, offset
+		return c.GetReverseDNSs(ctx, &page)
+	})
+}
+
+// AllTeammates returns an iterator over all teammates.
+func (c *Client) AllTeammates(ctx context.Context, input *InputGetTeammates) iter.Seq2[Teammate, error] {
+	in := InputGetTeammates{}
+	if input != nil {
+		in = *input
+	}
+	return offsetPages(ctx, in.Limit, in.Offset, func(limit, offset int) ([]Teammate, error) {
+		page := in
+		page.Limit, page.Offset = limit, offset
+		r, err := c.GetTeammates(ctx, &page)
+		if err != nil {
+			return nil, err
+		}
+		return r.Teammates, nil
+	})
+}
+
+// AllTemplates returns an iterator over all transactional templates matching
+// input, following the page_token of each page.
+func (c *Client) AllTemplates(ctx context.Context, input *InputGetTemplates) iter.Seq2[Template, error] {
+	in := InputGetTemplates{}
+	if input != nil {
+		in = *input
+	}
+	if in.PageSize <= 0 {
+		in.PageSize = defaultPageSize
+	}
+	return paginate(ctx, in.PageToken, func(token string) ([]Template, string, bool, error) {
+		page := in
+		page.PageToken = token
+		r, err := c.GetTemplates(ctx, &page)
+		if err != nil {
+			return nil, "", false, err
+		}
+		next := pageToken(r.Metadata.Next)
+		return r.Templates, next, next != "" && next != token, nil
+	})
+}
+
+// AllDesigns returns an iterator over all designs, following the page_token
+// of each page.
+func (c *Client) AllDesigns(ctx context.Context, input *InputGetDesigns) iter.Seq2[*Design, error] {
+	in := InputGetDesigns{}
+	if input != nil {
+		in = *input
+	}
+	if in.PageSize <= 0 {
+		in.PageSize = defaultPageSize
+	}
+	return paginate(ctx, in.PageToken, func(token string) ([]*Design, string, bool, error) {
+		page := in
+		page.PageToken = token
+		r, err := c.GetDesignsPage(ctx, &page)
+		if err != nil {
+			return nil, "", false, err
+		}
+		next := pageToken(r.Metadata.Next)
+		return r.Result, next, next != "" && next != token, nil
+	})
+}
+
+// AllTeammateSubuserAccess returns an iterator over the subuser access of a
+// teammate, following the after_subuser_id of each page.
+func (c *Client) AllTeammateSubuserAccess(ctx context.Context, teammateName string, input *InputGetTeammateSubuserAccess) iter.Seq2[SubuserAccess, error] {
+	in := InputGetTeammateSubuserAccess{}
+	if input != nil {
+		in = *input
+	}
+	if in.Limit <= 0 {
+		in.Limit = defaultPageSize
+	}
+	return paginate(ctx, in.AfterSubuserID, func(after int64) ([]SubuserAccess, int64, bool, error) {
+		page := in
+		page.AfterSubuserID = after
+		r, err := c.GetTeammateSubuserAccess(ctx, teammateName, &page)
+		if err != nil {
+			return nil, 0, false, err
+		}
+		next := r.Metadata.NextParams.AfterSubuserID
+		return r.SubuserAccess, next, next != 0 && next != after, nil
+	})
+}
+
+// AllVerifiedSenders returns an iterator over all verified senders, using the
+// ID of the last sender of each page as lastSeenID of the next one.
+func (c *Client) AllVerifiedSenders(ctx context.Context, input *InputGetVerifiedSenders) iter.Seq2[*VerifiedSender, error] {
+	in := InputGetVerifiedSenders{}
+	if input != nil {
+		in = *input
+	}
+	if in.Limit <= 0 {
+		in.Limit = defaultPageSize
+	}
+	return paginate(ctx, in.LastSeenID, func(lastSeenID int) ([]*VerifiedSender, int, bool, error) {
+		page := in
+		page.LastSeenID = lastSeenID
+		senders, err := c.GetVerifiedSenders(ctx, &page)
+		if err != nil || len(senders) == 0 {
+			return senders, 0, false, err
+		}
+		return senders, int(senders[len(senders)-1].ID), len(senders) >= in.Limit, nil
+	})
+}
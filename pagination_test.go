@@ -0,0 +1,186 @@
+package sendgrid
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllBounces(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	var calls int32
+	mux.HandleFunc("/suppression/bounces", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		atomic.AddInt32(&calls, 1)
+		assert.Equal(t, "2", r.URL.Query().Get("limit"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		switch offset {
+		case 0:
+			fmt.Fprint(w, `[{"email": "a@example.com"}, {"email": "b@example.com"}]`)
+		case 2:
+			fmt.Fprint(w, `[{"email": "c@example.com"}, {"email": "d@example.com"}]`)
+		case 4:
+			fmt.Fprint(w, `[{"email": "e@example.com"}]`)
+		default:
+			t.Errorf("unexpected offset %d", offset)
+		}
+	})
+
+	var emails []string
+	for bounce, err := range client.AllBounces(context.TODO(), &SuppressionListOptions{Limit: 2}) {
+		assert.NoError(t, err)
+		emails = append(emails, bounce.Email)
+	}
+	assert.Equal(t, []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com", "e@example.com"}, emails)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestAllBouncesBreak(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	var calls int32
+	mux.HandleFunc("/suppression/bounces", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		fmt.Fprint(w, `[{"email": "a@example.com"}, {"email": "b@example.com"}]`)
+	})
+
+	for bounce, err := range client.AllBounces(context.TODO(), &SuppressionListOptions{Limit: 2}) {
+		assert.NoError(t, err)
+		assert.Equal(t, "a@example.com", bounce.Email)
+		break
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestAllSubusersError(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/subusers", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("offset") == "" {
+			fmt.Fprint(w, `[{"id": 1, "username": "a"}]`)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	var (
+		usernames []string
+		errs      []error
+	)
+	for subuser, err := range client.AllSubusers(context.TODO(), &InputGetSubusers{Limit: 1}) {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		usernames = append(usernames, subuser.Username)
+	}
+	assert.Equal(t, []string{"a"}, usernames)
+	assert.Len(t, errs, 1)
+}
+
+func TestAllSubusersContextCanceled(t *testing.T) {
+	client, _, _, teardown := setup()
+	defer teardown()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, err := range client.AllSubusers(ctx, nil) {
+		assert.ErrorIs(t, err, context.Canceled)
+	}
+}
+
+func TestAllTemplates(t *testing.T) {
+	client, mux, serverURL, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/templates", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "dynamic", r.URL.Query().Get("generations"))
+		assert.Equal(t, "100", r.URL.Query().Get("page_size"))
+		switch r.URL.Query().Get("page_token") {
+		case "":
+			fmt.Fprintf(w, `{"result": [{"id": "d-1"}], "_metadata": {"next": "%s/v3/templates?page_size=100&page_token=abc"}}`, serverURL)
+		case "abc":
+			fmt.Fprint(w, `{"result": [{"id": "d-2"}], "_metadata": {}}`)
+		}
+	})
+
+	var ids []string
+	for tmpl, err := range client.AllTemplates(context.TODO(), &InputGetTemplates{Generations: "dynamic"}) {
+		assert.NoError(t, err)
+		ids = append(ids, tmpl.ID)
+	}
+	assert.Equal(t, []string{"d-1", "d-2"}, ids)
+}
+
+func TestAllDesigns(t *testing.T) {
+	client, mux, serverURL, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/designs", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("page_token") {
+		case "":
+			fmt.Fprintf(w, `{"result": [{"id": "1"}], "_metadata": {"next": "%s/v3/designs?page_token=next"}}`, serverURL)
+		case "next":
+			fmt.Fprint(w, `{"result": [{"id": "2"}], "_metadata": {}}`)
+		}
+	})
+
+	var ids []string
+	for design, err := range client.AllDesigns(context.TODO(), nil) {
+		assert.NoError(t, err)
+		ids = append(ids, design.ID)
+	}
+	assert.Equal(t, []string{"1", "2"}, ids)
+}
+
+func TestAllTeammateSubuserAccess(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/teammates/dummy/subuser_access", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("after_subuser_id") {
+		case "":
+			fmt.Fprint(w, `{"subuser_access": [{"id": 1}], "_metadata": {"next_params": {"limit": 1, "after_subuser_id": 1}}}`)
+		case "1":
+			fmt.Fprint(w, `{"subuser_access": [{"id": 2}], "_metadata": {"next_params": {"limit": 1}}}`)
+		}
+	})
+
+	var ids []int64
+	for access, err := range client.AllTeammateSubuserAccess(context.TODO(), "dummy", &InputGetTeammateSubuserAccess{Limit: 1}) {
+		assert.NoError(t, err)
+		ids = append(ids, access.ID)
+	}
+	assert.Equal(t, []int64{1, 2}, ids)
+}
+
+func TestAllVerifiedSenders(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/verified_senders", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("lastSeenID") {
+		case "":
+			fmt.Fprint(w, `{"results": [{"id": 10}, {"id": 11}]}`)
+		case "11":
+			fmt.Fprint(w, `{"results": [{"id": 12}]}`)
+		}
+	})
+
+	var ids []int64
+	for sender, err := range client.AllVerifiedSenders(context.TODO(), &InputGetVerifiedSenders{Limit: 2}) {
+		assert.NoError(t, err)
+		ids = append(ids, sender.ID)
+	}
+	assert.Equal(t, []int64{10, 11, 12}, ids)
+}
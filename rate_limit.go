@@ -0,0 +1,301 @@
+package sendgrid
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimitMode controls what Client.Do does before sending a request to an
+// endpoint whose rate limit is known to be exhausted.
+type RateLimitMode int
+
+const (
+	// RateLimitFailFast makes Do return *RateLimitedError without calling the API.
+	RateLimitFailFast RateLimitMode = iota
+	// RateLimitWait makes Do block until the rate limit resets.
+	RateLimitWait
+	// RateLimitDisabled turns off client side rate limit tracking.
+	RateLimitDisabled
+)
+
+// RateLimit is the rate limit state of an endpoint as reported by the
+// X-RateLimit-* response headers.
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// OptionRateLimitMode sets how the client reacts to exhausted rate limits.
+func OptionRateLimitMode(mode RateLimitMode) func(*Client) {
+	return func(c *Client) {
+		c.rateLimiter.setMode(mode)
+	}
+}
+
+// RateLimit returns the last known rate limit state for the endpoint that
+// req targets.
+func (c *Client) RateLimit(req *http.Request) (RateLimit, bool) {
+	return c.rateLimiter.get(c.rateLimitKey(req))
+}
+
+// rateLimiter tracks rate limit buckets per endpoint. It is safe for
+// concurrent use.
+type rateLimiter struct {
+	mu      sync.Mutex
+	mode    RateLimitMode
+	buckets map[string]*RateLimit
+	now     func() time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		buckets: map[string]*RateLimit{},
+		now:     time.Now,
+	}
+}
+
+// rateLimitKey returns the bucket key of req. SendGrid applies rate limits
+// per user and endpoint, so the key is the route of the endpoint, such as
+// "GET /templates/{}", and not the concrete path. The query string is not
+// part of the key either.
+func (c *Client) rateLimitKey(req *http.Request) string {
+	path := req.URL.Path
+	if c.baseURL != nil {
+		if p, ok := strings.CutPrefix(path, strings.TrimSuffix(c.baseURL.Path, "/")); ok {
+			path = p
+		}
+	}
+	key := req.Method + " " + rateLimitRoute(path)
+	if subuser := req.Header.Get("On-Behalf-Of"); subuser != "" {
+		key = subuser + ":" + key
+	}
+	return key
+}
+
+// rateLimitRoutes are the routes of the endpoints with path parameters,
+// written as "{}", and the static paths that would otherwise match one of
+// them.
+var rateLimitRoutes = splitRoutes(
+	"/access_settings/whitelist/{}",
+	"/alerts/{}",
+	"/api_keys/{}",
+	"/asm/groups/{}",
+	"/asm/groups/{}/suppressions",
+	"/asm/groups/{}/suppressions/{}",
+	"/contactdb/custom_fields/{}",
+	"/contactdb/segments/{}",
+	"/designs/{}",
+	"/ips/{}",
+	"/ips/pools",
+	"/ips/pools/{}",
+	"/ips/pools/{}/ips",
+	"/ips/pools/{}/ips/{}",
+	"/ips/warmup",
+	"/ips/warmup/{}",
+	"/mail/batch/{}",
+	"/sso/certificates/{}",
+	"/sso/integrations/{}",
+	"/sso/integrations/{}/certificates",
+	"/sso/teammates/{}",
+	"/subusers/{}",
+	"/subusers/{}/ips",
+	"/subusers/reputations",
+	"/subusers/stats",
+	"/suppression/blocks/{}",
+	"/suppression/bounces/{}",
+	"/suppression/invalid_emails/{}",
+	"/suppression/spam_reports/{}",
+	"/teammates/{}",
+	"/teammates/{}/subuser_access",
+	"/teammates/pending",
+	"/teammates/pending/{}",
+	"/templates/{}",
+	"/templates/{}/versions",
+	"/templates/{}/versions/{}",
+	"/templates/{}/versions/{}/activate",
+	"/user/scheduled_sends/{}",
+	"/user/webhooks/event/settings/all",
+	"/user/webhooks/event/settings/{}",
+	"/user/webhooks/event/settings/signed/{}",
+	"/user/webhooks/parse/settings/{}",
+	"/verified_senders/{}",
+	"/verified_senders/domains",
+	"/verified_senders/resend/{}",
+	"/verified_senders/steps_completed",
+	"/verified_senders/verify/{}",
+	"/whitelabel/domains/{}",
+	"/whitelabel/domains/{}/ips",
+	"/whitelabel/domains/{}/ips/{}",
+	"/whitelabel/domains/{}/subuser",
+	"/whitelabel/domains/{}/validate",
+	"/whitelabel/domains/subuser",
+	"/whitelabel/ips/{}",
+	"/whitelabel/ips/{}/validate",
+	"/whitelabel/links/{}",
+	"/whitelabel/links/{}/subuser",
+	"/whitelabel/links/{}/validate",
+	"/whitelabel/links/default",
+	"/whitelabel/links/subuser",
+)
+
+func splitRoutes(routes ...string) [][]string {
+	split := make([][]string, len(routes))
+	for i, route := range routes {
+		split[i] = strings.Split(route, "/")
+	}
+	return split
+}
+
+// rateLimitRoute returns the route of path. The route with the most literal
+// segments wins, so "/ips/pools" is not taken for "/ips/{}". Segments of
+// unknown paths that look like IDs or emails become "{}".
+func rateLimitRoute(path string) string {
+	segments := strings.Split(path, "/")
+	var best []string
+	bestLiterals := -1
+	for _, route := range rateLimitRoutes {
+		if len(route) != len(segments) {
+			continue
+		}
+		literals := 0
+		for i, seg := range route {
+			if seg == "{}" {
+				continue
+			}
+			if seg != segments[i] {
+				literals = -1
+				break
+			}
+			literals++
+		}
+		if literals > bestLiterals {
+			best, bestLiterals = route, literals
+		}
+	}
+	if best != nil {
+		return strings.Join(best, "/")
+	}
+
+	for i, seg := range segments {
+		if strings.ContainsAny(seg, "0123456789@%") {
+			segments[i] = "{}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func (r *rateLimiter) setMode(mode RateLimitMode) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.mode = mode
+}
+
+func (r *rateLimiter) get(key string) (RateLimit, bool) {
+	if r == nil {
+		return RateLimit{}, false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, ok := r.buckets[key]
+	if !ok {
+		return RateLimit{}, false
+	}
+	return *b, true
+}
+
+// reserve takes one request from the bucket of key. If the bucket is exhausted
+// and the reset time is in the future, it either waits for the reset or
+// returns *RateLimitedError depending on the mode.
+func (r *rateLimiter) reserve(ctx context.Context, key string) error {
+	if r == nil {
+		return nil
+	}
+
+	for {
+		r.mu.Lock()
+		if r.mode == RateLimitDisabled {
+			r.mu.Unlock()
+			return nil
+		}
+		b, ok := r.buckets[key]
+		if !ok {
+			r.mu.Unlock()
+			return nil
+		}
+		wait := b.Reset.Sub(r.now())
+		if wait <= 0 {
+			// the window has reset; the next response will tell the real numbers.
+			delete(r.buckets, key)
+			r.mu.Unlock()
+			return nil
+		}
+		if b.Remaining > 0 {
+			// account for in-flight requests from other goroutines
+			b.Remaining--
+			r.mu.Unlock()
+			return nil
+		}
+		mode := r.mode
+		r.mu.Unlock()
+
+		if mode != RateLimitWait {
+			return &RateLimitedError{RetryAfter: wait}
+		}
+		if err := sleepContext(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// update records the X-RateLimit-* headers of resp for key, and evicts the
+// buckets whose window has reset.
+func (r *rateLimiter) update(key string, resp *http.Response) {
+	if r == nil {
+		return
+	}
+
+	rl, ok := parseRateLimit(resp.Header)
+	if !ok {
+		return
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		rl.Remaining = 0
+	} else if resp.Header.Get("X-RateLimit-Remaining") == "" {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	for k, b := range r.buckets {
+		if !b.Reset.After(now) {
+			delete(r.buckets, k)
+		}
+	}
+	r.buckets[key] = &rl
+}
+
+// parseRateLimit parses the X-RateLimit-* headers. It reports false if the
+// reset time is missing or malformed.
+func parseRateLimit(h http.Header) (RateLimit, bool) {
+	reset, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return RateLimit{}, false
+	}
+	limit, _ := strconv.Atoi(h.Get("X-RateLimit-Limit"))
+	remaining, _ := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+
+	return RateLimit{
+		Limit:     limit,
+		Remaining: remaining,
+		Reset:     time.Unix(reset, 0),
+	}, true
+}
@@ -0,0 +1,199 @@
+package sendgrid
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitFailFast(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	var calls int32
+	reset := time.Now().Add(time.Minute).Unix()
+	mux.HandleFunc("/templates/d-12345abcde", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("X-RateLimit-Limit", "500")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+		fmt.Fprint(w, `{"id": "d-12345abcde"}`)
+	})
+
+	_, err := client.GetTemplate(context.TODO(), "d-12345abcde")
+	assert.NoError(t, err)
+
+	req, _ := client.NewRequest("GET", "/templates/d-12345abcde", nil)
+	rl, ok := client.RateLimit(req)
+	assert.True(t, ok)
+	assert.Equal(t, RateLimit{Limit: 500, Remaining: 0, Reset: time.Unix(reset, 0)}, rl)
+
+	_, err = client.GetTemplate(context.TODO(), "d-12345abcde")
+	var rateLimited *RateLimitedError
+	assert.ErrorAs(t, err, &rateLimited)
+	assert.Greater(t, rateLimited.RetryAfter, time.Duration(0))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// other endpoints are not affected
+	mux.HandleFunc("/templates", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		fmt.Fprint(w, `{"result": []}`)
+	})
+	_, err = client.GetTemplates(context.TODO(), &InputGetTemplates{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestRateLimitWait(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+	OptionRateLimitMode(RateLimitWait)(client)
+
+	var calls int32
+	mux.HandleFunc("/templates/d-12345abcde", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		fmt.Fprint(w, `{"id": "d-12345abcde"}`)
+	})
+
+	req, _ := client.NewRequest("GET", "/templates/d-12345abcde", nil)
+	client.rateLimiter.buckets[client.rateLimitKey(req)] = &RateLimit{Limit: 1, Remaining: 0, Reset: time.Now().Add(20 * time.Millisecond)}
+
+	_, err := client.GetTemplate(context.TODO(), "d-12345abcde")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestRateLimitWaitContextCanceled(t *testing.T) {
+	client, _, _, teardown := setup()
+	defer teardown()
+	OptionRateLimitMode(RateLimitWait)(client)
+
+	req, _ := client.NewRequest("GET", "/templates/d-12345abcde", nil)
+	client.rateLimiter.buckets[client.rateLimitKey(req)] = &RateLimit{Limit: 1, Remaining: 0, Reset: time.Now().Add(time.Hour)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := client.GetTemplate(ctx, "d-12345abcde")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestRateLimitDisabled(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+	OptionRateLimitMode(RateLimitDisabled)(client)
+
+	mux.HandleFunc("/templates/d-12345abcde", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": "d-12345abcde"}`)
+	})
+
+	req, _ := client.NewRequest("GET", "/templates/d-12345abcde", nil)
+	client.rateLimiter.buckets[client.rateLimitKey(req)] = &RateLimit{Limit: 1, Remaining: 0, Reset: time.Now().Add(time.Hour)}
+
+	_, err := client.GetTemplate(context.TODO(), "d-12345abcde")
+	assert.NoError(t, err)
+}
+
+func TestRateLimiterConcurrentReserve(t *testing.T) {
+	r := newRateLimiter()
+	r.buckets["GET /templates"] = &RateLimit{Limit: 10, Remaining: 5, Reset: time.Now().Add(time.Hour)}
+
+	var wg sync.WaitGroup
+	var allowed, denied int32
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := r.reserve(context.Background(), "GET /templates"); err != nil {
+				atomic.AddInt32(&denied, 1)
+				return
+			}
+			atomic.AddInt32(&allowed, 1)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(5), allowed)
+	assert.Equal(t, int32(15), denied)
+}
+
+func TestRateLimiterUpdateTooManyRequests(t *testing.T) {
+	r := newRateLimiter()
+	reset := time.Now().Add(time.Minute).Unix()
+	resp := &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     make(http.Header),
+	}
+	resp.Header.Set("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+
+	r.update("POST /mail/send", resp)
+
+	rl, ok := r.get("POST /mail/send")
+	assert.True(t, ok)
+	assert.Equal(t, 0, rl.Remaining)
+	assert.Equal(t, time.Unix(reset, 0), rl.Reset)
+}
+
+func TestRateLimitSharedAcrossIDs(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	var calls int32
+	reset := time.Now().Add(time.Minute).Unix()
+	mux.HandleFunc("/suppression/bounces/", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("X-RateLimit-Limit", "500")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	assert.NoError(t, client.DeleteBounce(context.TODO(), "a@example.com"))
+	err := client.DeleteBounce(context.TODO(), "b@example.com")
+	var rateLimited *RateLimitedError
+	assert.ErrorAs(t, err, &rateLimited)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	a, _ := client.NewRequest("DELETE", "/suppression/bounces/a%40example.com", nil)
+	b, _ := client.NewRequest("DELETE", "/suppression/bounces/b%40example.com", nil)
+	assert.Equal(t, "dummy:DELETE /suppression/bounces/{}", client.rateLimitKey(a))
+	assert.Equal(t, client.rateLimitKey(a), client.rateLimitKey(b))
+	assert.Len(t, client.rateLimiter.buckets, 1)
+}
+
+func TestRateLimitRoute(t *testing.T) {
+	for path, want := range map[string]string{
+		"/templates/d-12345abcde":            "/templates/{}",
+		"/templates/d-1/versions/v-2":        "/templates/{}/versions/{}",
+		"/asm/groups/1/suppressions/a@b.com": "/asm/groups/{}/suppressions/{}",
+		"/ips/pools":                         "/ips/pools",
+		"/ips/pools/marketing":               "/ips/pools/{}",
+		"/teammates/pending":                 "/teammates/pending",
+		"/teammates/alice":                   "/teammates/{}",
+		"/mail/send":                         "/mail/send",
+		"/unknown/12345/items":               "/unknown/{}/items",
+	} {
+		assert.Equal(t, want, rateLimitRoute(path), path)
+	}
+}
+
+func TestRateLimiterUpdateEvictsExpired(t *testing.T) {
+	r := newRateLimiter()
+	r.buckets["GET /templates/{}"] = &RateLimit{Limit: 1, Reset: time.Now().Add(-time.Second)}
+	resp := &http.Response{StatusCode: http.StatusOK, Header: make(http.Header)}
+	resp.Header.Set("X-RateLimit-Remaining", "10")
+	resp.Header.Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10))
+
+	r.update("POST /mail/send", resp)
+
+	_, ok := r.get("GET /templates/{}")
+	assert.False(t, ok)
+	assert.Len(t, r.buckets, 1)
+}
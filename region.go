@@ -0,0 +1,69 @@
+package sendgrid
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// Region is a SendGrid data residency region.
+type Region string
+
+const (
+	// RegionGlobal is the default region.
+	RegionGlobal Region = "global"
+	// RegionEU stores and processes data in the European Union.
+	RegionEU Region = "eu"
+)
+
+var regionBaseURLs = map[Region]string{
+	RegionGlobal: "https://api.sendgrid.com/v3",
+	RegionEU:     "https://api.eu.sendgrid.com/v3",
+}
+
+// OptionRegion selects the API host of the given data residency region.
+// An API key only works against the region it was created in; use
+// VerifyRegion to check it. OptionBaseURL given after OptionRegion overrides
+// the host but keeps the region.
+func OptionRegion(region Region) func(*Client) {
+	return func(c *Client) {
+		c.region = region
+		if endpoint, ok := regionBaseURLs[region]; ok {
+			c.baseURL, _ = url.Parse(endpoint)
+		}
+	}
+}
+
+// Region returns the data residency region of the client.
+func (c *Client) Region() Region {
+	if c.region == "" {
+		return RegionGlobal
+	}
+	return c.region
+}
+
+func (r Region) validate() error {
+	if _, ok := regionBaseURLs[r]; !ok {
+		return fmt.Errorf("unknown sendgrid region %q", r)
+	}
+	return nil
+}
+
+// VerifyRegion checks that the API key of the client is accepted by the host
+// of the client's region. SendGrid rejects keys used against another region
+// with 401 Unauthorized.
+// see: https://www.twilio.com/docs/sendgrid/api-reference/api-key-permissions/retrieve-a-list-of-scopes-for-which-this-user-has-access
+func (c *Client) VerifyRegion(ctx context.Context) error {
+	req, err := c.NewRequest("GET", "/scopes", nil)
+	if err != nil {
+		return err
+	}
+
+	if err := c.Do(ctx, req, nil); err != nil {
+		if IsUnauthorized(err) {
+			return fmt.Errorf("API key is not valid for sendgrid region %q: %w", c.Region(), err)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package sendgrid
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptionRegion(t *testing.T) {
+	client := New("test-api-key")
+	assert.Equal(t, RegionGlobal, client.Region())
+	assert.Equal(t, "https://api.sendgrid.com/v3", client.baseURL.String())
+
+	client = New("test-api-key", OptionRegion("eu"))
+	assert.Equal(t, RegionEU, client.Region())
+
+	req, err := client.NewRequest("GET", "/scopes", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://api.eu.sendgrid.com/v3/scopes", req.URL.String())
+}
+
+func TestOptionRegionUnknown(t *testing.T) {
+	client := New("test-api-key", OptionRegion("mars"))
+
+	_, err := client.NewRequest("GET", "/scopes", nil)
+	assert.EqualError(t, err, `unknown sendgrid region "mars"`)
+}
+
+func TestVerifyRegion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v3/scopes", r.URL.Path)
+		if r.Header.Get("Authorization") != "Bearer eu-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"errors": [{"field": null, "message": "authorization required"}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"scopes": ["mail.send"]}`)
+	}))
+	defer server.Close()
+
+	client := New("eu-key", OptionRegion(RegionEU), OptionBaseURL(server.URL+baseURLPath))
+	assert.Equal(t, RegionEU, client.Region())
+	assert.NoError(t, client.VerifyRegion(context.TODO()))
+
+	client = New("global-key", OptionRegion(RegionEU), OptionBaseURL(server.URL+baseURLPath))
+	err := client.VerifyRegion(context.TODO())
+	assert.ErrorContains(t, err, `API key is not valid for sendgrid region "eu"`)
+	assert.True(t, IsUnauthorized(err))
+}
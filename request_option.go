@@ -0,0 +1,92 @@
+package sendgrid
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// RequestOption customizes a single API call. Pass it to a client method
+// through WithRequestOptions.
+type RequestOption func(*requestOptions)
+
+type requestOptions struct {
+	subuser string
+	header  http.Header
+	timeout time.Duration
+}
+
+// RequestSubuser makes the call on behalf of the given subuser, overriding
+// OptionSubuser.
+func RequestSubuser(subuser string) RequestOption {
+	return func(o *requestOptions) {
+		o.subuser = subuser
+	}
+}
+
+// RequestHeader adds a header to the request.
+func RequestHeader(key, value string) RequestOption {
+	return func(o *requestOptions) {
+		if o.header == nil {
+			o.header = http.Header{}
+		}
+		o.header.Add(key, value)
+	}
+}
+
+// RequestTimeout limits the total duration of the call, including retries.
+func RequestTimeout(d time.Duration) RequestOption {
+	return func(o *requestOptions) {
+		o.timeout = d
+	}
+}
+
+type requestOptionsKey struct{}
+
+// WithRequestOptions returns a copy of ctx that applies opts to the calls ctx
+// is passed to. Options already present in ctx are kept and applied first.
+//
+//	ctx = sendgrid.WithRequestOptions(ctx, sendgrid.RequestSubuser("acme"))
+//	templates, err := c.GetTemplates(ctx, input)
+func WithRequestOptions(ctx context.Context, opts ...RequestOption) context.Context {
+	prev, _ := ctx.Value(requestOptionsKey{}).([]RequestOption)
+	return context.WithValue(ctx, requestOptionsKey{}, append(slices.Clip(prev), opts...))
+}
+
+func requestOptionsFromContext(ctx context.Context) *requestOptions {
+	opts, _ := ctx.Value(requestOptionsKey{}).([]RequestOption)
+	if len(opts) == 0 {
+		return nil
+	}
+	o := &requestOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// apply sets the headers of o on req.
+func (o *requestOptions) apply(req *http.Request) {
+	if o == nil {
+		return
+	}
+	if o.subuser != "" {
+		req.Header.Set("On-Behalf-Of", o.subuser)
+	}
+	for k, vs := range o.header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+}
+
+// WithSubuser returns a copy of the client that makes calls on behalf of the
+// given subuser. The copy shares the HTTP client, middleware and rate limit
+// state with c, so it is cheap to create per call.
+func (c *Client) WithSubuser(subuser string) *Client {
+	clone := *c
+	clone.subuser = subuser
+	clone.middleware = slices.Clip(c.middleware)
+	return &clone
+}
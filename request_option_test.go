@@ -0,0 +1,93 @@
+package sendgrid
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithRequestOptions(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/templates/d-12345abcde", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		assert.Equal(t, "acme", r.Header.Get("On-Behalf-Of"))
+		assert.Equal(t, []string{"a", "b"}, r.Header.Values("X-Custom"))
+		fmt.Fprint(w, `{"id": "d-12345abcde"}`)
+	})
+
+	ctx := WithRequestOptions(context.TODO(), RequestSubuser("acme"), RequestHeader("X-Custom", "a"))
+	ctx = WithRequestOptions(ctx, RequestHeader("X-Custom", "b"))
+
+	got, err := client.GetTemplate(ctx, "d-12345abcde")
+	assert.NoError(t, err)
+	assert.Equal(t, "d-12345abcde", got.ID)
+}
+
+func TestWithRequestOptionsKeepsRequest(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/test", func(w http.ResponseWriter, r *http.Request) {})
+
+	req, err := client.NewRequest("GET", "/test", nil)
+	assert.NoError(t, err)
+
+	err = client.Do(WithRequestOptions(context.TODO(), RequestSubuser("acme")), req, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "dummy", req.Header.Get("On-Behalf-Of"))
+}
+
+func TestRequestTimeout(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/templates/d-12345abcde", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	})
+
+	ctx := WithRequestOptions(context.TODO(), RequestTimeout(10*time.Millisecond))
+	_, err := client.GetTemplate(ctx, "d-12345abcde")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestClientWithSubuser(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	reset := time.Now().Add(time.Minute).Unix()
+	mux.HandleFunc("/templates/d-12345abcde", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Limit", "500")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+		fmt.Fprintf(w, `{"id": "d-12345abcde", "name": %q}`, r.Header.Get("On-Behalf-Of"))
+	})
+
+	acme := client.WithSubuser("acme")
+	assert.Equal(t, "dummy", client.subuser)
+	assert.Equal(t, "acme", acme.subuser)
+	assert.Same(t, client.httpclient, acme.httpclient)
+	assert.Same(t, client.rateLimiter, acme.rateLimiter)
+
+	got, err := acme.GetTemplate(context.TODO(), "d-12345abcde")
+	assert.NoError(t, err)
+	assert.Equal(t, "acme", got.Name)
+
+	// the exhausted bucket of acme does not affect the parent client
+	got, err = client.GetTemplate(context.TODO(), "d-12345abcde")
+	assert.NoError(t, err)
+	assert.Equal(t, "dummy", got.Name)
+
+	_, err = client.WithSubuser("acme").GetTemplate(context.TODO(), "d-12345abcde")
+	var rateLimited *RateLimitedError
+	assert.ErrorAs(t, err, &rateLimited)
+}
@@ -0,0 +1,59 @@
+package sendgrid
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// ResponseMeta holds metadata of the HTTP response of an API call.
+type ResponseMeta struct {
+	// StatusCode is the HTTP status code of the last response.
+	StatusCode int
+	// Header is the header of the last response.
+	Header http.Header
+	// RateLimit is parsed from the X-RateLimit-* headers. It is the zero
+	// value if the response did not carry them.
+	RateLimit RateLimit
+	// RequestID is the value of the X-Request-Id header, if any.
+	RequestID string
+	// Attempts is the number of attempts made, including retries.
+	Attempts int
+	// Duration is the total time spent in the call, including retries.
+	Duration time.Duration
+}
+
+type responseMetaKey struct{}
+
+// WithResponseMeta returns a copy of ctx that makes the client fill meta with
+// the response metadata of the call ctx is passed to.
+//
+//	var meta sendgrid.ResponseMeta
+//	bounces, err := c.GetBounces(sendgrid.WithResponseMeta(ctx, &meta), input)
+//	log.Println(meta.StatusCode, meta.RateLimit.Remaining)
+func WithResponseMeta(ctx context.Context, meta *ResponseMeta) context.Context {
+	return context.WithValue(ctx, responseMetaKey{}, meta)
+}
+
+func responseMetaFromContext(ctx context.Context) *ResponseMeta {
+	meta, _ := ctx.Value(responseMetaKey{}).(*ResponseMeta)
+	return meta
+}
+
+// record fills m from resp. resp may be nil if no response was received.
+func (m *ResponseMeta) record(resp *http.Response, attempts int, d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.Attempts = attempts
+	m.Duration = d
+	if resp == nil {
+		return
+	}
+	m.StatusCode = resp.StatusCode
+	m.Header = resp.Header
+	m.RequestID = resp.Header.Get("X-Request-Id")
+	if rl, ok := parseRateLimit(resp.Header); ok {
+		m.RateLimit = rl
+	}
+}
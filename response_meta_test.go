@@ -0,0 +1,56 @@
+package sendgrid
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithResponseMeta(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	reset := time.Now().Add(time.Minute).Unix()
+	mux.HandleFunc("/templates", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		w.Header().Set("X-Request-Id", "1c4b2d0e")
+		w.Header().Set("X-RateLimit-Limit", "500")
+		w.Header().Set("X-RateLimit-Remaining", "499")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id": "733ba07f-ead1-41fc-933a-3976baa23716", "name": "example"}`)
+	})
+
+	var meta ResponseMeta
+	got, err := client.CreateTemplate(WithResponseMeta(context.TODO(), &meta), &InputCreateTemplate{Name: "example"})
+	assert.NoError(t, err)
+	assert.Equal(t, "733ba07f-ead1-41fc-933a-3976baa23716", got.ID)
+
+	assert.Equal(t, http.StatusCreated, meta.StatusCode)
+	assert.Equal(t, "1c4b2d0e", meta.RequestID)
+	assert.Equal(t, RateLimit{Limit: 500, Remaining: 499, Reset: time.Unix(reset, 0)}, meta.RateLimit)
+	assert.Equal(t, 1, meta.Attempts)
+	assert.Equal(t, "1c4b2d0e", meta.Header.Get("X-Request-Id"))
+}
+
+func TestWithResponseMetaOnError(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+	OptionRetry(testRetryPolicy())(client)
+
+	mux.HandleFunc("/suppression/bounces", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	var meta ResponseMeta
+	_, err := client.GetBounces(WithResponseMeta(context.TODO(), &meta), nil)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, meta.StatusCode)
+	assert.Equal(t, 3, meta.Attempts)
+}
@@ -0,0 +1,176 @@
+package sendgrid
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// RetryPolicy configures how Client.Do retries failed requests.
+// A nil policy (the default) disables retries.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts including the first one.
+	// Values less than 1 are treated as 1.
+	MaxAttempts int
+	// MinBackoff is the base delay before the first retry.
+	MinBackoff time.Duration
+	// MaxBackoff caps the exponential backoff delay.
+	MaxBackoff time.Duration
+	// RetryableMethods lists the HTTP methods that may be retried after a
+	// server error or a transport error. If empty, idempotent methods
+	// (GET, HEAD, OPTIONS, PUT, DELETE) are retried.
+	RetryableMethods []string
+	// RetryableStatusCodes lists the HTTP status codes that trigger a retry.
+	// If empty, 502, 503 and 504 are retried.
+	// 429 responses are always retried regardless of method because
+	// SendGrid rejects them before processing the request.
+	RetryableStatusCodes []int
+}
+
+var (
+	defaultRetryableMethods     = []string{http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete}
+	defaultRetryableStatusCodes = []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+)
+
+// DefaultRetryPolicy returns a RetryPolicy with sensible defaults.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 4,
+		MinBackoff:  500 * time.Millisecond,
+		MaxBackoff:  30 * time.Second,
+	}
+}
+
+// OptionRetry enables automatic retries for the client.
+func OptionRetry(p *RetryPolicy) func(*Client) {
+	return func(c *Client) {
+		c.retry = p
+	}
+}
+
+func (p *RetryPolicy) maxAttempts() int {
+	if p == nil || p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// shouldRetry reports whether a request that ended with the given status code
+// (0 if no response was received) and error is worth another attempt.
+func (p *RetryPolicy) shouldRetry(req *http.Request, statusCode int, err error) bool {
+	if err == nil {
+		return false
+	}
+
+	// the body cannot be sent again
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+
+	var rateLimited *RateLimitedError
+	if errors.As(err, &rateLimited) {
+		return true
+	}
+
+	methods := p.RetryableMethods
+	if len(methods) == 0 {
+		methods = defaultRetryableMethods
+	}
+	if !slices.Contains(methods, req.Method) {
+		return false
+	}
+
+	if statusCode != 0 {
+		return p.retryableStatus(statusCode)
+	}
+
+	return isTransientError(err)
+}
+
+// retryableStatus reports whether statusCode is in RetryableStatusCodes, or
+// in the defaults if the list is empty.
+func (p *RetryPolicy) retryableStatus(statusCode int) bool {
+	codes := p.RetryableStatusCodes
+	if len(codes) == 0 {
+		codes = defaultRetryableStatusCodes
+	}
+	return slices.Contains(codes, statusCode)
+}
+
+// backoff returns the delay before the given retry attempt (1-based).
+// A *RateLimitedError with a positive RetryAfter takes precedence.
+func (p *RetryPolicy) backoff(attempt int, err error) time.Duration {
+	var rateLimited *RateLimitedError
+	if errors.As(err, &rateLimited) && rateLimited.RetryAfter > 0 {
+		return rateLimited.RetryAfter
+	}
+
+	d := p.MinBackoff
+	for i := 1; i < attempt && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+
+	// equal jitter: half fixed, half random
+	half := d / 2
+	return half + rand.N(half+1)
+}
+
+// isTransientError reports whether err is a transport error that is likely to
+// succeed on retry.
+func isTransientError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+// rewindBody resets req.Body so that the request can be sent again.
+func rewindBody(req *http.Request) error {
+	if req.GetBody == nil {
+		return nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return err
+	}
+	req.Body = body
+	return nil
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,182 @@
+package sendgrid
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 3,
+		MinBackoff:  time.Millisecond,
+		MaxBackoff:  5 * time.Millisecond,
+	}
+}
+
+func TestDoRetryServerError(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+	OptionRetry(testRetryPolicy())(client)
+
+	var calls int32
+	mux.HandleFunc("/templates/d-12345abcde", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"id": "d-12345abcde"}`)
+	})
+
+	got, err := client.GetTemplate(context.TODO(), "d-12345abcde")
+	assert.NoError(t, err)
+	assert.Equal(t, "d-12345abcde", got.ID)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestDoRetryExhausted(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+	OptionRetry(testRetryPolicy())(client)
+
+	var calls int32
+	mux.HandleFunc("/templates/d-12345abcde", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	_, err := client.GetTemplate(context.TODO(), "d-12345abcde")
+	assert.Error(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestDoRetryRateLimitedWithoutReset(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+	OptionRetry(testRetryPolicy())(client)
+
+	var calls int32
+	mux.HandleFunc("/templates/d-12345abcde", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, `{"id": "d-12345abcde"}`)
+	})
+
+	got, err := client.GetTemplate(context.TODO(), "d-12345abcde")
+	assert.NoError(t, err)
+	assert.Equal(t, "d-12345abcde", got.ID)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestDoRetryNonIdempotentMethod(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+	OptionRetry(testRetryPolicy())(client)
+
+	var calls int32
+	mux.HandleFunc("/templates", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	_, err := client.CreateTemplate(context.TODO(), &InputCreateTemplate{Name: "example"})
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestDoRetryRateLimitedRewindsBody(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+	OptionRetry(testRetryPolicy())(client)
+
+	var calls int32
+	mux.HandleFunc("/templates", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.JSONEq(t, `{"name": "example", "generation": "dynamic"}`, string(body))
+
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Unix(), 10))
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id": "733ba07f-ead1-41fc-933a-3976baa23716", "name": "example"}`)
+	})
+
+	got, err := client.CreateTemplate(context.TODO(), &InputCreateTemplate{Name: "example", Generation: "dynamic"})
+	assert.NoError(t, err)
+	assert.Equal(t, "733ba07f-ead1-41fc-933a-3976baa23716", got.ID)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestDoRetryContextCanceled(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+	OptionRetry(&RetryPolicy{MaxAttempts: 5, MinBackoff: time.Hour, MaxBackoff: time.Hour})(client)
+
+	mux.HandleFunc("/templates/d-12345abcde", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := client.GetTemplate(ctx, "d-12345abcde")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := &RetryPolicy{MinBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	for attempt := 1; attempt <= 6; attempt++ {
+		d := p.backoff(attempt, nil)
+		assert.LessOrEqual(t, d, time.Second)
+		assert.GreaterOrEqual(t, d, 50*time.Millisecond)
+	}
+
+	d := p.backoff(1, &RateLimitedError{RetryAfter: 3 * time.Second})
+	assert.Equal(t, 3*time.Second, d)
+}
+
+func TestRetryPolicyShouldRetry(t *testing.T) {
+	p := &RetryPolicy{MaxAttempts: 3}
+	get, _ := http.NewRequest("GET", "https://api.sendgrid.com/v3/templates", nil)
+	post, _ := http.NewRequest("POST", "https://api.sendgrid.com/v3/templates", nil)
+
+	tests := []struct {
+		name       string
+		req        *http.Request
+		statusCode int
+		err        error
+		expected   bool
+	}{
+		{"no error", get, 200, nil, false},
+		{"service unavailable", get, 503, &APIError{StatusCode: 503}, true},
+		{"internal server error", get, 500, &APIError{StatusCode: 500}, false},
+		{"post service unavailable", post, 503, &APIError{StatusCode: 503}, false},
+		{"post rate limited", post, 429, &RateLimitedError{}, true},
+		{"unexpected eof", get, 0, io.ErrUnexpectedEOF, true},
+		{"context canceled", get, 0, context.Canceled, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, p.shouldRetry(tt.req, tt.statusCode, tt.err))
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package sendgrid
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// OptionSandbox forces sandbox mode on every mail sent with SendMail, and
+// with the helpers built on it such as BulkSender and Outbox. SendGrid
+// validates mail sent in sandbox mode but does not deliver it. The
+// MailSettings of the caller's InputSendMail are left untouched.
+func OptionSandbox(b bool) func(*Client) {
+	return func(c *Client) {
+		c.sandbox = b
+	}
+}
+
+// OptionDryRun enables dry-run mode. In dry-run mode, POST, PATCH, PUT and
+// DELETE requests are not sent: the would-be request is logged, with
+// secrets redacted as described by DumpOptions, and a synthetic empty
+// response with the header X-Dry-Run: true is returned. Its status is 202
+// for mail send, 204 for DELETE and 200 otherwise, so output values are
+// left empty. Other requests are sent as usual.
+func OptionDryRun(b bool) func(*Client) {
+	return func(c *Client) {
+		c.dryRun = b
+	}
+}
+
+// withSandbox returns a copy of input with sandbox mode enabled.
+func withSandbox(input *InputSendMail) *InputSendMail {
+	m := *input
+	settings := MailSettings{}
+	if input.MailSettings != nil {
+		settings = *input.MailSettings
+	}
+	settings.SandBoxMode = &Setting{Enable: Bool(true)}
+	m.MailSettings = &settings
+	return &m
+}
+
+// isMutating reports whether a request with the given method changes state.
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPatch, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// dryRunResponse logs req and returns the synthetic response of dry-run mode.
+func (c *Client) dryRunResponse(req *http.Request) (*http.Response, error) {
+	if err := logRequest(req, dryRunLog{c}, c.dump); err != nil {
+		return nil, err
+	}
+
+	code := http.StatusOK
+	switch {
+	case req.Method == http.MethodDelete:
+		code = http.StatusNoContent
+	case req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/mail/send"):
+		code = http.StatusAccepted
+	}
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", code, http.StatusText(code)),
+		StatusCode: code,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{"X-Dry-Run": {"true"}},
+		Body:       http.NoBody,
+		Request:    req,
+	}, nil
+}
+
+// dryRunLog logs the requests of dry-run mode whether or not debugging is
+// enabled.
+type dryRunLog struct {
+	c *Client
+}
+
+func (d dryRunLog) Debug() bool {
+	return true
+}
+
+func (d dryRunLog) Debugf(format string, v ...interface{}) {
+	d.Debugln(fmt.Sprintf(format, v...))
+}
+
+func (d dryRunLog) Debugln(v ...interface{}) {
+	msg := "dry run: " + fmt.Sprintln(v...)
+	if d.c.slog != nil {
+		d.c.slog.Info(strings.TrimSuffix(msg, "\n"))
+		return
+	}
+	// a logging failure must not abort or fail the dry run
+	_ = d.c.log.Output(2, msg)
+}
@@ -0,0 +1,127 @@
+package sendgrid
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptionSandbox(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+	OptionSandbox(true)(client)
+
+	mux.HandleFunc("/mail/send", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPost)
+		var body InputSendMail
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if assert.NotNil(t, body.MailSettings) && assert.NotNil(t, body.MailSettings.SandBoxMode) {
+			assert.True(t, *body.MailSettings.SandBoxMode.Enable)
+		}
+		if assert.NotNil(t, body.MailSettings.Footer) {
+			assert.True(t, *body.MailSettings.Footer.Enable)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	input := NewInputSendMail()
+	input.SetFrom(NewEmail("from@example.com", ""))
+	input.MailSettings = &MailSettings{Footer: &FooterSetting{Enable: Bool(true)}}
+	_, err := client.SendMail(context.TODO(), input)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+		return
+	}
+	assert.Nil(t, input.MailSettings.SandBoxMode)
+}
+
+func TestOptionDryRun(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+	var buf bytes.Buffer
+	OptionLog(log.New(&buf, "", 0))(client)
+	OptionDryRun(true)(client)
+
+	mux.HandleFunc("/mail/send", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("mail/send should not be called in dry-run mode")
+	})
+	mux.HandleFunc("/mail/batch/", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("mail/batch should not be called in dry-run mode")
+	})
+	mux.HandleFunc("/user/scheduled_sends", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		if _, err := w.Write([]byte(`[{"batch_id":"id","status":"pause"}]`)); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	input := NewInputSendMail()
+	input.SetFrom(NewEmail("from@example.com", ""))
+	input.SetSubject("dry run")
+	_, err := client.SendMail(context.TODO(), input)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+		return
+	}
+	assert.Contains(t, buf.String(), "dry run: POST ")
+	assert.Contains(t, buf.String(), `"subject":"dry run"`)
+	assert.NotContains(t, buf.String(), "test-token")
+
+	err = client.DeleteScheduledSend(context.TODO(), "id")
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+		return
+	}
+	assert.True(t, strings.Contains(buf.String(), "dry run: DELETE "))
+
+	sends, err := client.GetScheduledSends(context.TODO())
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+		return
+	}
+	assert.Len(t, sends, 1)
+}
+
+func TestOptionDryRun_ResponseMeta(t *testing.T) {
+	client, _, _, teardown := setup()
+	defer teardown()
+	OptionLog(log.New(&bytes.Buffer{}, "", 0))(client)
+	OptionDryRun(true)(client)
+
+	req, err := client.NewRequest(http.MethodDelete, "/mail/batch/id", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.dryRunResponse(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, "true", resp.Header.Get("X-Dry-Run"))
+}
+
+func TestOptionDryRun_LogError(t *testing.T) {
+	originalLogFatal := logFatal
+	defer func() {
+		logFatal = originalLogFatal
+	}()
+	logFatal = mockLogFatal
+	fatalCalled = false
+
+	client, _, _, teardown := setup()
+	defer teardown()
+	OptionLog(&errorLogger{shouldError: true})(client)
+	OptionDryRun(true)(client)
+
+	err := client.DeleteScheduledSend(context.TODO(), "id")
+	assert.NoError(t, err)
+	assert.False(t, fatalCalled)
+}
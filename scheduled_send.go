@@ -0,0 +1,243 @@
+package sendgrid
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Statuses of a scheduled send.
+const (
+	ScheduledSendPause  = "pause"
+	ScheduledSendCancel = "cancel"
+)
+
+type OutputCreateBatchID struct {
+	BatchID string `json:"batch_id,omitempty"`
+}
+
+// CreateBatchID generates a batch ID. Assign it to InputSendMail.BatchID to
+// be able to pause or cancel the scheduled sends of the batch.
+// see: https://www.twilio.com/docs/sendgrid/api-reference/cancel-scheduled-sends/create-a-batch-id
+func (c *Client) CreateBatchID(ctx context.Context) (*OutputCreateBatchID, error) {
+	req, err := c.NewRequest("POST", "/mail/batch", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	r := new(OutputCreateBatchID)
+	if err := c.Do(ctx, req, &r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+type OutputGetBatchID struct {
+	BatchID string `json:"batch_id,omitempty"`
+}
+
+// GetBatchID validates a batch ID. It returns an error if the batch ID is
+// unknown.
+// see: https://www.twilio.com/docs/sendgrid/api-reference/cancel-scheduled-sends/validate-a-batch-id
+func (c *Client) GetBatchID(ctx context.Context, batchID string) (*OutputGetBatchID, error) {
+	path := fmt.Sprintf("/mail/batch/%s", batchID)
+
+	req, err := c.NewRequest("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	r := new(OutputGetBatchID)
+	if err := c.Do(ctx, req, &r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+type ScheduledSend struct {
+	BatchID string `json:"batch_id,omitempty"`
+	Status  string `json:"status,omitempty"`
+}
+
+// see: https://www.twilio.com/docs/sendgrid/api-reference/cancel-scheduled-sends/retrieve-all-scheduled-sends
+func (c *Client) GetScheduledSends(ctx context.Context) ([]*ScheduledSend, error) {
+	req, err := c.NewRequest("GET", "/user/scheduled_sends", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	r := []*ScheduledSend{}
+	if err := c.Do(ctx, req, &r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// see: https://www.twilio.com/docs/sendgrid/api-reference/cancel-scheduled-sends/retrieve-scheduled-send
+func (c *Client) GetScheduledSend(ctx context.Context, batchID string) ([]*ScheduledSend, error) {
+	path := fmt.Sprintf("/user/scheduled_sends/%s", batchID)
+
+	req, err := c.NewRequest("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	r := []*ScheduledSend{}
+	if err := c.Do(ctx, req, &r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+type InputCreateScheduledSend struct {
+	BatchID string `json:"batch_id"`
+	Status  string `json:"status"`
+}
+
+type OutputCreateScheduledSend struct {
+	BatchID string `json:"batch_id,omitempty"`
+	Status  string `json:"status,omitempty"`
+}
+
+// CreateScheduledSend pauses or cancels the scheduled sends of a batch.
+// see: https://www.twilio.com/docs/sendgrid/api-reference/cancel-scheduled-sends/cancel-or-pause-a-scheduled-send
+func (c *Client) CreateScheduledSend(ctx context.Context, input *InputCreateScheduledSend) (*OutputCreateScheduledSend, error) {
+	req, err := c.NewRequest("POST", "/user/scheduled_sends", input)
+	if err != nil {
+		return nil, err
+	}
+
+	r := new(OutputCreateScheduledSend)
+	if err := c.Do(ctx, req, &r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+type InputUpdateScheduledSend struct {
+	Status string `json:"status"`
+}
+
+// UpdateScheduledSend changes the status of a paused or canceled batch.
+// see: https://www.twilio.com/docs/sendgrid/api-reference/cancel-scheduled-sends/update-a-scheduled-send
+func (c *Client) UpdateScheduledSend(ctx context.Context, batchID string, input *InputUpdateScheduledSend) error {
+	path := fmt.Sprintf("/user/scheduled_sends/%s", batchID)
+
+	req, err := c.NewRequest("PATCH", path, input)
+	if err != nil {
+		return err
+	}
+
+	if err := c.Do(ctx, req, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteScheduledSend removes the pause or cancellation of a batch, so that
+// its scheduled sends are delivered.
+// see: https://www.twilio.com/docs/sendgrid/api-reference/cancel-scheduled-sends/delete-a-cancellation-or-pause-from-a-scheduled-send
+func (c *Client) DeleteScheduledSend(ctx context.Context, batchID string) error {
+	path := fmt.Sprintf("/user/scheduled_sends/%s", batchID)
+
+	req, err := c.NewRequest("DELETE", path, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := c.Do(ctx, req, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ScheduledMail is a handle to mail sent with ScheduleMail. It can pause,
+// cancel or resume the delivery until the scheduled time.
+type ScheduledMail struct {
+	client *Client
+
+	// BatchID is the batch ID of the mail.
+	BatchID string
+	// SendAt is the scheduled delivery time.
+	SendAt time.Time
+	// MessageID is the X-Message-Id of the send request.
+	MessageID string
+
+	mu     sync.Mutex
+	status string
+}
+
+// ScheduleMail sends input for delivery at the given time and returns a
+// handle to pause or cancel it. A batch ID is created unless input already
+// has one. input is not modified.
+func (c *Client) ScheduleMail(ctx context.Context, input *InputSendMail, at time.Time) (*ScheduledMail, error) {
+	ctx = withDefaultOperationName(ctx, "ScheduleMail")
+	m := *input
+	m.SetSendAt(at)
+	if m.BatchID == "" {
+		batch, err := c.CreateBatchID(ctx)
+		if err != nil {
+			return nil, err
+		}
+		m.BatchID = batch.BatchID
+	}
+
+	out, err := c.SendMail(ctx, &m)
+	if err != nil {
+		return nil, err
+	}
+	return &ScheduledMail{
+		client:    c,
+		BatchID:   m.BatchID,
+		SendAt:    time.Unix(m.SendAt, 0),
+		MessageID: out.MessageID,
+	}, nil
+}
+
+// Cancel cancels the delivery. Canceled mail is discarded at the scheduled
+// time.
+func (s *ScheduledMail) Cancel(ctx context.Context) error {
+	return s.setStatus(ctx, ScheduledSendCancel)
+}
+
+// Pause pauses the delivery. Paused mail is discarded if it is not resumed
+// within 72 hours of the scheduled time.
+func (s *ScheduledMail) Pause(ctx context.Context) error {
+	return s.setStatus(ctx, ScheduledSendPause)
+}
+
+// Resume removes a pause or cancellation, so that the mail is delivered.
+func (s *ScheduledMail) Resume(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.status == "" {
+		return nil
+	}
+	if err := s.client.DeleteScheduledSend(ctx, s.BatchID); err != nil {
+		return err
+	}
+	s.status = ""
+	return nil
+}
+
+// setStatus creates the scheduled send of the batch, or updates it if it
+// has been paused or canceled before.
+func (s *ScheduledMail) setStatus(ctx context.Context, status string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch s.status {
+	case status:
+		return nil
+	case "":
+		if _, err := s.client.CreateScheduledSend(ctx, &InputCreateScheduledSend{BatchID: s.BatchID, Status: status}); err != nil {
+			return err
+		}
+	default:
+		if err := s.client.UpdateScheduledSend(ctx, s.BatchID, &InputUpdateScheduledSend{Status: status}); err != nil {
+			return err
+		}
+	}
+	s.status = status
+	return nil
+}
@@ -0,0 +1,170 @@
+package sendgrid
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateBatchID(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/mail/batch", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPost)
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"batch_id":"YOUR_BATCH_ID"}`)
+	})
+
+	got, err := client.CreateBatchID(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, &OutputCreateBatchID{BatchID: "YOUR_BATCH_ID"}, got)
+}
+
+func TestGetBatchID(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/mail/batch/YOUR_BATCH_ID", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprint(w, `{"batch_id":"YOUR_BATCH_ID"}`)
+	})
+	mux.HandleFunc("/mail/batch/unknown", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"errors":[{"field":null,"message":"invalid batch id"}]}`)
+	})
+
+	got, err := client.GetBatchID(context.TODO(), "YOUR_BATCH_ID")
+	assert.Nil(t, err)
+	assert.Equal(t, "YOUR_BATCH_ID", got.BatchID)
+
+	_, err = client.GetBatchID(context.TODO(), "unknown")
+	assert.NotNil(t, err)
+}
+
+func TestGetScheduledSends(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/user/scheduled_sends", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprint(w, `[{"batch_id":"a","status":"pause"},{"batch_id":"b","status":"cancel"}]`)
+	})
+	mux.HandleFunc("/user/scheduled_sends/a", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprint(w, `[{"batch_id":"a","status":"pause"}]`)
+	})
+
+	all, err := client.GetScheduledSends(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, []*ScheduledSend{{BatchID: "a", Status: "pause"}, {BatchID: "b", Status: "cancel"}}, all)
+
+	one, err := client.GetScheduledSend(context.TODO(), "a")
+	assert.Nil(t, err)
+	assert.Equal(t, []*ScheduledSend{{BatchID: "a", Status: "pause"}}, one)
+}
+
+func TestCreateScheduledSend(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/user/scheduled_sends", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPost)
+		b, _ := io.ReadAll(r.Body)
+		assert.JSONEq(t, `{"batch_id":"a","status":"cancel"}`, string(b))
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, string(b))
+	})
+
+	got, err := client.CreateScheduledSend(context.TODO(), &InputCreateScheduledSend{BatchID: "a", Status: ScheduledSendCancel})
+	assert.Nil(t, err)
+	assert.Equal(t, &OutputCreateScheduledSend{BatchID: "a", Status: "cancel"}, got)
+}
+
+func TestUpdateScheduledSend(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/user/scheduled_sends/a", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPatch)
+		b, _ := io.ReadAll(r.Body)
+		assert.JSONEq(t, `{"status":"pause"}`, string(b))
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	err := client.UpdateScheduledSend(context.TODO(), "a", &InputUpdateScheduledSend{Status: ScheduledSendPause})
+	assert.Nil(t, err)
+}
+
+func TestDeleteScheduledSend(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/user/scheduled_sends/a", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodDelete)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	err := client.DeleteScheduledSend(context.TODO(), "a")
+	assert.Nil(t, err)
+}
+
+func TestScheduleMail(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	var calls []string
+	mux.HandleFunc("/mail/batch", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"batch_id":"batch-1"}`)
+	})
+	mux.HandleFunc("/mail/send", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		var m InputSendMail
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&m))
+		assert.Equal(t, "batch-1", m.BatchID)
+		assert.NotZero(t, m.SendAt)
+		w.Header().Set("X-Message-Id", "msg-1")
+		w.WriteHeader(http.StatusAccepted)
+	})
+	mux.HandleFunc("/user/scheduled_sends", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"batch_id":"batch-1","status":"pause"}`)
+	})
+	mux.HandleFunc("/user/scheduled_sends/batch-1", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	input := NewInputSendMail()
+	at := time.Now().Add(time.Hour).Truncate(time.Second)
+	s, err := client.ScheduleMail(context.TODO(), input, at)
+	assert.Nil(t, err)
+	assert.Equal(t, "batch-1", s.BatchID)
+	assert.Equal(t, "msg-1", s.MessageID)
+	assert.True(t, at.Equal(s.SendAt))
+	assert.Empty(t, input.BatchID)
+	assert.Zero(t, input.SendAt)
+
+	assert.Nil(t, s.Pause(context.TODO()))
+	assert.Nil(t, s.Pause(context.TODO()))
+	assert.Nil(t, s.Cancel(context.TODO()))
+	assert.Nil(t, s.Resume(context.TODO()))
+	assert.Nil(t, s.Resume(context.TODO()))
+
+	assert.Equal(t, []string{
+		"POST /mail/batch",
+		"POST /mail/send",
+		"POST /user/scheduled_sends",
+		"PATCH /user/scheduled_sends/batch-1",
+		"DELETE /user/scheduled_sends/batch-1",
+	}, calls)
+}
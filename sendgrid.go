@@ -7,11 +7,13 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/google/go-querystring/query"
 	"github.com/pkg/errors"
@@ -29,9 +31,18 @@ type Client struct {
 	apiKey     string
 	baseURL    *url.URL
 	debug      bool
+	dump       *DumpOptions
 	log        ilogger
+	slog       *slog.Logger
 	httpclient httpClient
 	subuser    string
+	region     Region
+	retry      *RetryPolicy
+	middleware []Middleware
+	sandbox    bool
+	dryRun     bool
+
+	rateLimiter *rateLimiter
 }
 
 // Option defines an option for a Client
@@ -59,7 +70,9 @@ func OptionHTTPClient(client httpClient) func(*Client) {
 	}
 }
 
-// OptionDebug enable debugging for the client
+// OptionDebug enable debugging for the client. In debug mode every request
+// and response is dumped to the log, with secrets redacted as described by
+// DumpOptions.
 func OptionDebug(b bool) func(*Client) {
 	return func(c *Client) {
 		c.debug = b
@@ -76,10 +89,11 @@ func OptionLog(l logger) func(*Client) {
 // New builds a sendgrid client from the provided token, baseURL and options
 func New(apiKey string, options ...Option) *Client {
 	s := &Client{
-		apiKey:     apiKey,
-		baseURL:    defaultBaseURL,
-		httpclient: &http.Client{},
-		log:        log.New(os.Stderr, "i10416/sendgrid", log.LstdFlags|log.Lshortfile),
+		apiKey:      apiKey,
+		baseURL:     defaultBaseURL,
+		httpclient:  &http.Client{},
+		rateLimiter: newRateLimiter(),
+		log:         log.New(os.Stderr, "i10416/sendgrid", log.LstdFlags|log.Lshortfile),
 	}
 
 	for _, opt := range options {
@@ -91,6 +105,10 @@ func New(apiKey string, options ...Option) *Client {
 
 // Debugf print a formatted debug line.
 func (c *Client) Debugf(format string, v ...interface{}) {
+	if c.debug && c.slog != nil {
+		c.slogDebug(fmt.Sprintf(format, v...))
+		return
+	}
 	if c.debug {
 		if err := c.log.Output(2, fmt.Sprintf(format, v...)); err != nil {
 			c.Debugln(err)
@@ -100,6 +118,10 @@ func (c *Client) Debugf(format string, v ...interface{}) {
 
 // Debugln print a debug line.
 func (c *Client) Debugln(v ...interface{}) {
+	if c.debug && c.slog != nil {
+		c.slogDebug(fmt.Sprintln(v...))
+		return
+	}
 	if c.debug {
 		if err := c.log.Output(2, fmt.Sprintln(v...)); err != nil {
 			c.Debugln(err)
@@ -130,6 +152,10 @@ func (c *Client) NewRequest(method, urlStr string, body interface{}) (*http.Requ
 		return nil, fmt.Errorf("baseURL is nil")
 	}
 
+	if err := c.Region().validate(); err != nil {
+		return nil, err
+	}
+
 	if strings.HasSuffix(c.baseURL.Path, "/") {
 		return nil, fmt.Errorf("baseURL must not have a trailing slash, but %q does", c.baseURL)
 	}
@@ -174,16 +200,98 @@ func (c *Client) NewRequest(method, urlStr string, body interface{}) (*http.Requ
 // error if an API error has occurred. If v implements the io.Writer
 // interface, the raw response body will be written to v, without attempting to
 // first decode it. If rate limit is exceeded and reset time is in the future,
-// Do returns *RateLimitError immediately without making a network API call.
+// Do returns *RateLimitedError immediately without making a network API call,
+// unless the client is configured with RateLimitWait, in which case Do waits
+// for the reset.
+//
+// If the client has a RetryPolicy, Do retries rate limited requests, server
+// errors and transient transport errors according to the policy.
+//
+// If ctx carries a *ResponseMeta set by WithResponseMeta, it is filled with
+// the metadata of the last response. Each attempt passes through the
+// middleware registered with OptionMiddleware. Options set by
+// WithRequestOptions are applied to req before it is sent.
 //
 // The provided ctx must be non-nil, if it is nil an error is returned. If it is canceled or times out,
 // ctx.Err() will be returned.
 func (c *Client) Do(ctx context.Context, req *http.Request, v interface{}) error {
+	_, err := c.doResponse(ctx, req, v)
+	return err
+}
+
+// doResponse is like Do but also returns the last HTTP response received, or
+// nil if none was received. The body of the returned response is closed.
+func (c *Client) doResponse(ctx context.Context, req *http.Request, v interface{}) (resp *http.Response, err error) {
 	if ctx == nil {
-		return errors.New("context must be non-nil")
+		return nil, errors.New("context must be non-nil")
 	}
 
-	req = req.WithContext(ctx)
+	if OperationName(ctx) == "" && (len(c.middleware) > 0 || c.slog != nil) {
+		if name := callerOperation(); name != "" {
+			ctx = WithOperationName(ctx, name)
+		}
+	}
+
+	opts := requestOptionsFromContext(ctx)
+	if opts != nil && opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+		defer cancel()
+	}
+
+	if opts != nil {
+		// clone so that headers of the caller's request are left untouched
+		req = req.Clone(ctx)
+		opts.apply(req)
+	} else {
+		req = req.WithContext(ctx)
+	}
+	h := c.handler(v)
+
+	attempt := 0
+	start := time.Now()
+	defer func() {
+		responseMetaFromContext(ctx).record(resp, attempt, time.Since(start))
+	}()
+
+	for attempt = 1; ; attempt++ {
+		t := time.Now()
+		resp, err = h(ctx, req)
+		c.logAttempt(ctx, req, resp, err, attempt, time.Since(t))
+		statusCode := 0
+		if resp != nil {
+			statusCode = resp.StatusCode
+		}
+		if attempt >= c.retry.maxAttempts() || !c.retry.shouldRetry(req, statusCode, err) {
+			return resp, err
+		}
+
+		wait := c.retry.backoff(attempt, err)
+		c.Debugf("retrying %s %s in %s (attempt %d): %v", req.Method, req.URL.Path, wait, attempt, err)
+		if er := sleepContext(ctx, wait); er != nil {
+			return resp, er
+		}
+		if er := rewindBody(req); er != nil {
+			return resp, er
+		}
+	}
+}
+
+// do performs a single attempt of req. It returns the HTTP response, or nil
+// if no response was received.
+func (c *Client) do(ctx context.Context, req *http.Request, v interface{}) (*http.Response, error) {
+	if c.dryRun && isMutating(req.Method) {
+		return c.dryRunResponse(req)
+	}
+
+	key := c.rateLimitKey(req)
+	if err := c.rateLimiter.reserve(ctx, key); err != nil {
+		return nil, err
+	}
+
+	if err := logRequest(req, c, c.dump); err != nil {
+		return nil, err
+	}
 
 	resp, err := c.httpclient.Do(req)
 	if err != nil {
@@ -191,11 +299,11 @@ func (c *Client) Do(ctx context.Context, req *http.Request, v interface{}) error
 		// the context's error is probably more useful.
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
+			return nil, ctx.Err()
 		default:
 		}
 
-		return err
+		return nil, err
 	}
 	defer func() {
 		if er := resp.Body.Close(); er != nil {
@@ -203,15 +311,21 @@ func (c *Client) Do(ctx context.Context, req *http.Request, v interface{}) error
 		}
 	}()
 
-	err = checkStatusCode(resp, c)
+	c.rateLimiter.update(key, resp)
+
+	if err := logResponse(resp, c, c.dump); err != nil {
+		return resp, err
+	}
+
+	err = checkStatusCode(resp)
 	if err != nil {
-		return err
+		return resp, err
 	}
 
 	if v != nil {
 		if w, ok := v.(io.Writer); ok {
 			if _, er := io.Copy(w, resp.Body); er != nil {
-				return er
+				return resp, er
 			}
 		} else {
 			decErr := json.NewDecoder(resp.Body).Decode(v)
@@ -224,7 +338,7 @@ func (c *Client) Do(ctx context.Context, req *http.Request, v interface{}) error
 		}
 	}
 
-	return err
+	return resp, err
 }
 
 // AddOptions adds the parameters in opt as URL query parameters to s. opt
@@ -0,0 +1,279 @@
+package sendgridtest
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/i10416/sendgrid"
+)
+
+type subuser struct {
+	sendgrid.Subuser
+	IPs []string `json:"-"`
+}
+
+// Subusers returns the stored subusers.
+func (s *Server) Subusers() []sendgrid.Subuser {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	subusers := make([]sendgrid.Subuser, 0, len(s.subusers))
+	for _, u := range s.subusers {
+		subusers = append(subusers, u.Subuser)
+	}
+	return subusers
+}
+
+// SubuserIPs returns the IP addresses assigned to a subuser.
+func (s *Server) SubuserIPs(username string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range s.subusers {
+		if u.Username == username {
+			return slices.Clone(u.IPs)
+		}
+	}
+	return nil
+}
+
+// AddTeammate stores an active teammate, as if they had accepted an
+// invitation.
+func (s *Server) AddTeammate(t sendgrid.OutputGetTeammate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t.UserType == "" {
+		t.UserType = "teammate"
+		if t.IsAdmin {
+			t.UserType = "admin"
+		}
+	}
+	s.teammates = append(s.teammates, &t)
+}
+
+// Teammates returns the stored teammates.
+func (s *Server) Teammates() []sendgrid.OutputGetTeammate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	teammates := make([]sendgrid.OutputGetTeammate, 0, len(s.teammates))
+	for _, t := range s.teammates {
+		teammates = append(teammates, *t)
+	}
+	return teammates
+}
+
+// PendingTeammates returns the invitations that have not been accepted.
+func (s *Server) PendingTeammates() []sendgrid.PendingTeammate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pending := make([]sendgrid.PendingTeammate, 0, len(s.pendingTeammates))
+	for _, t := range s.pendingTeammates {
+		pending = append(pending, *t)
+	}
+	return pending
+}
+
+func (s *Server) accountRoutes() {
+	s.handle("GET /subusers", s.getSubusers)
+	s.handle("POST /subusers", s.createSubuser)
+	s.handle("PATCH /subusers/{username}", s.updateSubuserStatus)
+	s.handle("PUT /subusers/{username}/ips", s.updateSubuserIPs)
+	s.handle("DELETE /subusers/{username}", s.deleteSubuser)
+
+	s.handle("GET /teammates", s.getTeammates)
+	s.handle("POST /teammates", s.inviteTeammate)
+	s.handle("GET /teammates/pending", s.getPendingTeammates)
+	s.handle("DELETE /teammates/pending/{token}", s.deletePendingTeammate)
+	s.handle("GET /teammates/{username}", s.getTeammate)
+	s.handle("PATCH /teammates/{username}", s.updateTeammatePermissions)
+	s.handle("DELETE /teammates/{username}", s.deleteTeammate)
+}
+
+func (s *Server) findSubuser(w http.ResponseWriter, r *http.Request) (int, *subuser) {
+	username := r.PathValue("username")
+	i := slices.IndexFunc(s.subusers, func(u *subuser) bool { return u.Username == username })
+	if i < 0 {
+		writeNotFound(w, "subuser")
+		return -1, nil
+	}
+	return i, s.subusers[i]
+}
+
+func (s *Server) getSubusers(w http.ResponseWriter, r *http.Request) {
+	username := r.URL.Query().Get("username")
+	matched := []*subuser{}
+	for _, u := range s.subusers {
+		if username == "" || u.Username == username {
+			matched = append(matched, u)
+		}
+	}
+	paged, ok := page(w, r, matched)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, paged)
+}
+
+func (s *Server) createSubuser(w http.ResponseWriter, r *http.Request) {
+	var in sendgrid.InputCreateSubuser
+	if !decode(w, r, &in) {
+		return
+	}
+	switch {
+	case in.Username == "":
+		writeError(w, http.StatusBadRequest, "username", "username is required")
+		return
+	case in.Email == "":
+		writeError(w, http.StatusBadRequest, "email", "email is required")
+		return
+	case in.Password == "":
+		writeError(w, http.StatusBadRequest, "password", "password is required")
+		return
+	case slices.ContainsFunc(s.subusers, func(u *subuser) bool { return u.Username == in.Username }):
+		writeError(w, http.StatusBadRequest, "username", "username exists")
+		return
+	}
+
+	u := &subuser{
+		Subuser: sendgrid.Subuser{
+			ID:       s.id(),
+			Username: in.Username,
+			Email:    in.Email,
+		},
+		IPs: in.Ips,
+	}
+	s.subusers = append(s.subusers, u)
+	writeJSON(w, http.StatusCreated, sendgrid.OutputCreateSubuser{
+		UserID:           u.ID,
+		Username:         u.Username,
+		Email:            u.Email,
+		CreditAllocation: sendgrid.CreditAllocation{Type: "unlimited"},
+	})
+}
+
+func (s *Server) updateSubuserStatus(w http.ResponseWriter, r *http.Request) {
+	_, u := s.findSubuser(w, r)
+	if u == nil {
+		return
+	}
+	var in sendgrid.InputUpdateSubuserStatus
+	if !decode(w, r, &in) {
+		return
+	}
+	u.Disabled = in.Disabled
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) updateSubuserIPs(w http.ResponseWriter, r *http.Request) {
+	_, u := s.findSubuser(w, r)
+	if u == nil {
+		return
+	}
+	var ips []string
+	if !decode(w, r, &ips) {
+		return
+	}
+	u.IPs = ips
+	writeJSON(w, http.StatusOK, ips)
+}
+
+func (s *Server) deleteSubuser(w http.ResponseWriter, r *http.Request) {
+	if i, u := s.findSubuser(w, r); u != nil {
+		s.subusers = slices.Delete(s.subusers, i, i+1)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) findTeammate(w http.ResponseWriter, r *http.Request) (int, *sendgrid.OutputGetTeammate) {
+	username := r.PathValue("username")
+	i := slices.IndexFunc(s.teammates, func(t *sendgrid.OutputGetTeammate) bool { return t.Username == username })
+	if i < 0 {
+		writeNotFound(w, "teammate")
+		return -1, nil
+	}
+	return i, s.teammates[i]
+}
+
+func (s *Server) getTeammates(w http.ResponseWriter, r *http.Request) {
+	paged, ok := page(w, r, s.teammates)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Result []*sendgrid.OutputGetTeammate `json:"result"`
+	}{paged})
+}
+
+func (s *Server) getTeammate(w http.ResponseWriter, r *http.Request) {
+	if _, t := s.findTeammate(w, r); t != nil {
+		writeJSON(w, http.StatusOK, t)
+	}
+}
+
+func (s *Server) inviteTeammate(w http.ResponseWriter, r *http.Request) {
+	var in sendgrid.InputInviteTeammate
+	if !decode(w, r, &in) {
+		return
+	}
+	if in.Email == "" {
+		writeError(w, http.StatusBadRequest, "email", "email is required")
+		return
+	}
+
+	p := &sendgrid.PendingTeammate{
+		Email:          in.Email,
+		Scopes:         in.Scopes,
+		IsAdmin:        in.IsAdmin,
+		Token:          fmt.Sprintf("token-%d", s.id()),
+		ExpirationDate: int(s.now().Add(7 * 24 * time.Hour).Unix()),
+	}
+	s.pendingTeammates = append(s.pendingTeammates, p)
+	writeJSON(w, http.StatusCreated, sendgrid.OutputInviteTeammate{
+		Token:   p.Token,
+		Email:   p.Email,
+		IsAdmin: p.IsAdmin,
+		Scopes:  p.Scopes,
+	})
+}
+
+func (s *Server) getPendingTeammates(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, struct {
+		Result []*sendgrid.PendingTeammate `json:"result"`
+	}{s.pendingTeammates})
+}
+
+func (s *Server) deletePendingTeammate(w http.ResponseWriter, r *http.Request) {
+	token := r.PathValue("token")
+	i := slices.IndexFunc(s.pendingTeammates, func(p *sendgrid.PendingTeammate) bool { return p.Token == token })
+	if i < 0 {
+		writeNotFound(w, "pending teammate")
+		return
+	}
+	s.pendingTeammates = slices.Delete(s.pendingTeammates, i, i+1)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) updateTeammatePermissions(w http.ResponseWriter, r *http.Request) {
+	_, t := s.findTeammate(w, r)
+	if t == nil {
+		return
+	}
+	var in sendgrid.InputUpdateTeammatePermissions
+	if !decode(w, r, &in) {
+		return
+	}
+	t.IsAdmin = in.IsAdmin
+	t.Scopes = in.Scopes
+	t.UserType = "teammate"
+	if t.IsAdmin {
+		t.UserType = "admin"
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+func (s *Server) deleteTeammate(w http.ResponseWriter, r *http.Request) {
+	if i, t := s.findTeammate(w, r); t != nil {
+		s.teammates = slices.Delete(s.teammates, i, i+1)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,68 @@
+package sendgridtest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/i10416/sendgrid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubusers(t *testing.T) {
+	fake, client := setup(t)
+	ctx := context.TODO()
+
+	created, err := client.CreateSubuser(ctx, &sendgrid.InputCreateSubuser{Username: "acme", Email: "acme@example.com", Password: "secret", Ips: []string{"192.0.2.1"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "acme", created.Username)
+
+	_, err = client.CreateSubuser(ctx, &sendgrid.InputCreateSubuser{Username: "acme", Email: "acme@example.com", Password: "secret"})
+	assert.Error(t, err)
+
+	assert.NoError(t, client.UpdateSubuserStatus(ctx, "acme", &sendgrid.InputUpdateSubuserStatus{Disabled: true}))
+	assert.NoError(t, client.UpdateSubuserIps(ctx, "acme", []string{"192.0.2.2"}))
+	assert.Equal(t, []string{"192.0.2.2"}, fake.SubuserIPs("acme"))
+
+	subusers, err := client.GetSubusers(ctx, &sendgrid.InputGetSubusers{Username: "acme"})
+	assert.NoError(t, err)
+	if assert.Len(t, subusers, 1) {
+		assert.Equal(t, created.UserID, subusers[0].ID)
+		assert.True(t, subusers[0].Disabled)
+	}
+
+	assert.NoError(t, client.DeleteSubuser(ctx, "acme"))
+	assert.Empty(t, fake.Subusers())
+}
+
+func TestTeammates(t *testing.T) {
+	fake, client := setup(t)
+	ctx := context.TODO()
+
+	invite, err := client.InviteTeammate(ctx, &sendgrid.InputInviteTeammate{Email: "new@example.com", Scopes: []string{"mail.send"}})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, invite.Token)
+
+	pending, err := client.GetPendingTeammates(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, pending.PendingTeammates, 1)
+	assert.NoError(t, client.DeletePendingTeammate(ctx, invite.Token))
+	assert.Empty(t, fake.PendingTeammates())
+
+	fake.AddTeammate(sendgrid.OutputGetTeammate{Username: "alice", Email: "alice@example.com"})
+
+	teammates, err := client.GetTeammates(ctx, &sendgrid.InputGetTeammates{})
+	assert.NoError(t, err)
+	assert.Len(t, teammates.Teammates, 1)
+
+	updated, err := client.UpdateTeammatePermissions(ctx, "alice", &sendgrid.InputUpdateTeammatePermissions{IsAdmin: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "admin", updated.UserType)
+
+	got, err := client.GetTeammate(ctx, "alice")
+	assert.NoError(t, err)
+	assert.True(t, got.IsAdmin)
+
+	assert.NoError(t, client.DeleteTeammate(ctx, "alice"))
+	_, err = client.GetTeammate(ctx, "alice")
+	assert.True(t, sendgrid.IsNotFound(err))
+}
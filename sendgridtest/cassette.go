@@ -0,0 +1,233 @@
+package sendgridtest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/i10416/sendgrid/internal/redact"
+)
+
+// redacted replaces secrets in recorded interactions.
+const redacted = "REDACTED"
+
+// Interaction is a request/response pair stored in a cassette.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is the request of an Interaction.
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// RecordedResponse is the response of an Interaction.
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// Cassette records the HTTP interactions of a sendgrid.Client to a JSONL
+// file, one Interaction per line, or replays them from one. Pass it to
+// sendgrid.OptionHTTPClient.
+//
+//	cassette, err := sendgridtest.Record("testdata/templates.jsonl", http.DefaultClient)
+//	c := sendgrid.New(apiKey, sendgrid.OptionHTTPClient(cassette))
+//	defer cassette.Close()
+//
+// The Authorization header and the values of secret fields such as password
+// and api_key are redacted before an interaction is written.
+type Cassette struct {
+	client *http.Client
+	file   *os.File
+
+	mu           sync.Mutex
+	interactions []*Interaction
+	used         []bool
+}
+
+// Record returns a cassette that sends requests with client and appends each
+// interaction to the file at path, which is truncated first. A nil client
+// means http.DefaultClient.
+func Record(path string, client *http.Client) (*Cassette, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Cassette{client: client, file: f}, nil
+}
+
+// Replay returns a cassette that serves the interactions stored in the file
+// at path. Each interaction is served at most once, in recorded order, to a
+// request with the same method, path, query and body. Requests without a
+// matching interaction fail.
+func Replay(path string) (*Cassette, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	c := &Cassette{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		i := new(Interaction)
+		if err := json.Unmarshal(scanner.Bytes(), i); err != nil {
+			return nil, fmt.Errorf("sendgridtest: %s:%d: %w", path, line, err)
+		}
+		c.interactions = append(c.interactions, i)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	c.used = make([]bool, len(c.interactions))
+	return c, nil
+}
+
+// Close closes the file a recording cassette writes to. It is a no-op for
+// replaying cassettes.
+func (c *Cassette) Close() error {
+	if c.file == nil {
+		return nil
+	}
+	return c.file.Close()
+}
+
+// Unused returns the interactions that have not been replayed yet.
+func (c *Cassette) Unused() []Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var unused []Interaction
+	for i, used := range c.used {
+		if !used {
+			unused = append(unused, *c.interactions[i])
+		}
+	}
+	return unused
+}
+
+// Do records or replays req.
+func (c *Cassette) Do(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	if c.file == nil {
+		return c.replay(req, body)
+	}
+	return c.record(req, body)
+}
+
+func (c *Cassette) record(req *http.Request, body []byte) (*http.Response, error) {
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	header := req.Header.Clone()
+	if header.Get("Authorization") != "" {
+		header.Set("Authorization", redacted)
+	}
+	i := &Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    req.URL.RequestURI(),
+			Header: header,
+			Body:   redactBody(body),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header.Clone(),
+			Body:       redactBody(respBody),
+		},
+	}
+	line, err := json.Marshal(i)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.file.Write(append(line, '\n')); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *Cassette) replay(req *http.Request, body []byte) (*http.Response, error) {
+	uri, b := req.URL.RequestURI(), redactBody(body)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for n, i := range c.interactions {
+		if c.used[n] || i.Request.Method != req.Method || i.Request.URL != uri || i.Request.Body != b {
+			continue
+		}
+		c.used[n] = true
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", i.Response.StatusCode, http.StatusText(i.Response.StatusCode)),
+			StatusCode:    i.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        i.Response.Header.Clone(),
+			Body:          io.NopCloser(bytes.NewBufferString(i.Response.Body)),
+			ContentLength: int64(len(i.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("sendgridtest: no recorded interaction for %s %s", req.Method, uri)
+}
+
+// readRequestBody reads the body of req and replaces it so that it can be
+// sent again.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// redactBody replaces the values of secret fields in a JSON body.
+// JSON bodies are re-encoded, so equivalent bodies compare equal; other
+// bodies are returned unchanged.
+func redactBody(body []byte) string {
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return string(body)
+	}
+	b, err := json.Marshal(redact.Secrets(v, redacted))
+	if err != nil {
+		return string(body)
+	}
+	return string(b)
+}
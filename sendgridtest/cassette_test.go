@@ -0,0 +1,71 @@
+package sendgridtest
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/i10416/sendgrid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCassette(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.jsonl")
+	ctx := context.TODO()
+
+	fake := NewServer()
+	recorder, err := Record(path, &http.Client{})
+	assert.NoError(t, err)
+
+	client := sendgrid.New("secret-api-key", sendgrid.OptionBaseURL(fake.URL), sendgrid.OptionHTTPClient(recorder))
+	created, err := client.CreateSubuser(ctx, &sendgrid.InputCreateSubuser{Username: "acme", Email: "acme@example.com", Password: "hunter2"})
+	assert.NoError(t, err)
+	_, err = client.GetSubuserReputations(ctx, "acme")
+	assert.Error(t, err)
+	assert.NoError(t, recorder.Close())
+	fake.Close()
+
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(b), "secret-api-key")
+	assert.NotContains(t, string(b), "hunter2")
+	assert.Contains(t, string(b), redacted)
+
+	player, err := Replay(path)
+	assert.NoError(t, err)
+	assert.Len(t, player.Unused(), 2)
+
+	client = sendgrid.New("another-key", sendgrid.OptionBaseURL(fake.URL), sendgrid.OptionHTTPClient(player))
+	replayed, err := client.CreateSubuser(ctx, &sendgrid.InputCreateSubuser{Username: "acme", Email: "acme@example.com", Password: "different"})
+	assert.NoError(t, err)
+	assert.Equal(t, created, replayed)
+
+	_, err = client.GetSubuserReputations(ctx, "acme")
+	var apiErr *sendgrid.APIError
+	if assert.ErrorAs(t, err, &apiErr) {
+		assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	}
+	assert.Empty(t, player.Unused())
+
+	// each interaction is replayed once
+	_, err = client.CreateSubuser(ctx, &sendgrid.InputCreateSubuser{Username: "acme", Email: "acme@example.com", Password: "hunter2"})
+	assert.ErrorContains(t, err, "no recorded interaction for POST /v3/subusers")
+}
+
+func TestCassetteUnmatchedBody(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.jsonl")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"request": {"method": "POST", "url": "/v3/templates", "body": "{\"name\":\"a\"}"}, "response": {"status_code": 201, "body": "{\"id\":\"1\"}"}}`+"\n"), 0o644))
+
+	player, err := Replay(path)
+	assert.NoError(t, err)
+	client := sendgrid.New("key", sendgrid.OptionBaseURL("https://api.sendgrid.com/v3"), sendgrid.OptionHTTPClient(player))
+
+	_, err = client.CreateTemplate(context.TODO(), &sendgrid.InputCreateTemplate{Name: "b"})
+	assert.ErrorContains(t, err, "no recorded interaction")
+
+	got, err := client.CreateTemplate(context.TODO(), &sendgrid.InputCreateTemplate{Name: "a"})
+	assert.NoError(t, err)
+	assert.Equal(t, "1", got.ID)
+}
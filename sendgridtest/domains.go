@@ -0,0 +1,348 @@
+package sendgridtest
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/i10416/sendgrid"
+)
+
+type ipPool struct {
+	Name string   `json:"name"`
+	IPs  []string `json:"ips,omitempty"`
+}
+
+// AuthenticatedDomains returns the stored authenticated domains.
+func (s *Server) AuthenticatedDomains() []sendgrid.DomainAuthentication {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	domains := make([]sendgrid.DomainAuthentication, 0, len(s.domains))
+	for _, d := range s.domains {
+		domains = append(domains, *d)
+	}
+	return domains
+}
+
+// SetDomainValid sets whether the DNS records of an authenticated domain pass
+// validation. Domains start out invalid, as if their records had not been
+// published yet.
+func (s *Server) SetDomainValid(id int64, valid bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, d := range s.domains {
+		if d.ID == id {
+			d.DNS.MailCname.Valid = valid
+			d.DNS.Dkim1.Valid = valid
+			d.DNS.Dkim2.Valid = valid
+		}
+	}
+}
+
+// IPPools returns the stored IP pools and the IP addresses in each of them.
+func (s *Server) IPPools() map[string][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pools := make(map[string][]string, len(s.ipPools))
+	for _, p := range s.ipPools {
+		pools[p.Name] = slices.Clone(p.IPs)
+	}
+	return pools
+}
+
+func (s *Server) domainRoutes() {
+	s.handle("GET /whitelabel/domains", s.getAuthenticatedDomains)
+	s.handle("POST /whitelabel/domains", s.authenticateDomain)
+	s.handle("GET /whitelabel/domains/default", s.getDefaultAuthentication)
+	s.handle("GET /whitelabel/domains/{id}", s.getAuthenticatedDomain)
+	s.handle("PATCH /whitelabel/domains/{id}", s.updateDomainAuthentication)
+	s.handle("DELETE /whitelabel/domains/{id}", s.deleteAuthenticatedDomain)
+	s.handle("POST /whitelabel/domains/{id}/validate", s.validateDomainAuthentication)
+	s.handle("POST /whitelabel/domains/{id}/ips", s.addIPToAuthenticatedDomain)
+	s.handle("DELETE /whitelabel/domains/{id}/ips/{ip}", s.removeIPFromAuthenticatedDomain)
+
+	s.handle("GET /ips/pools", s.getIPPools)
+	s.handle("POST /ips/pools", s.createIPPool)
+	s.handle("GET /ips/pools/{name}", s.getIPPool)
+	s.handle("PUT /ips/pools/{name}", s.updateIPPool)
+	s.handle("DELETE /ips/pools/{name}", s.deleteIPPool)
+	s.handle("POST /ips/pools/{name}/ips", s.addIPToPool)
+	s.handle("DELETE /ips/pools/{name}/ips/{ip}", s.removeIPFromPool)
+}
+
+func (s *Server) findDomain(w http.ResponseWriter, r *http.Request) (int, *sendgrid.DomainAuthentication) {
+	id, ok := pathInt(r, "id")
+	i := slices.IndexFunc(s.domains, func(d *sendgrid.DomainAuthentication) bool { return d.ID == id })
+	if !ok || i < 0 {
+		writeNotFound(w, "authenticated domain")
+		return -1, nil
+	}
+	return i, s.domains[i]
+}
+
+func (s *Server) getAuthenticatedDomains(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	matched := []*sendgrid.DomainAuthentication{}
+	for _, d := range s.domains {
+		if q.Get("domain") != "" && d.Domain != q.Get("domain") {
+			continue
+		}
+		if q.Get("username") != "" && d.Username != q.Get("username") {
+			continue
+		}
+		if q.Get("exclude_subusers") == "true" && d.Username != "" {
+			continue
+		}
+		matched = append(matched, d)
+	}
+	paged, ok := page(w, r, matched)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, paged)
+}
+
+func (s *Server) authenticateDomain(w http.ResponseWriter, r *http.Request) {
+	var in sendgrid.InputAuthenticateDomain
+	if !decode(w, r, &in) {
+		return
+	}
+	if in.Domain == "" {
+		writeError(w, http.StatusBadRequest, "domain", "domain is required")
+		return
+	}
+	if in.Subdomain == "" {
+		in.Subdomain = "em"
+	}
+
+	id := s.id()
+	host := in.Subdomain + "." + in.Domain
+	d := &sendgrid.DomainAuthentication{
+		ID:                id,
+		UserID:            1,
+		Subdomain:         in.Subdomain,
+		Domain:            in.Domain,
+		Username:          in.Username,
+		IPs:               in.IPs,
+		CustomSpf:         in.CustomSpf,
+		AutomaticSecurity: in.AutomaticSecurity,
+		DNS: sendgrid.DNS{
+			MailCname: sendgrid.Record{Type: "cname", Host: host, Data: fmt.Sprintf("u%d.wl.sendgrid.net", id)},
+			Dkim1:     sendgrid.Record{Type: "cname", Host: "s1._domainkey." + in.Domain, Data: fmt.Sprintf("s1.domainkey.u%d.wl.sendgrid.net", id)},
+			Dkim2:     sendgrid.Record{Type: "cname", Host: "s2._domainkey." + in.Domain, Data: fmt.Sprintf("s2.domainkey.u%d.wl.sendgrid.net", id)},
+		},
+	}
+	s.domains = append(s.domains, d)
+	if in.Default {
+		s.setDefaultDomain(d)
+	}
+	writeJSON(w, http.StatusCreated, d)
+}
+
+// setDefaultDomain makes d the only default domain.
+func (s *Server) setDefaultDomain(d *sendgrid.DomainAuthentication) {
+	for _, other := range s.domains {
+		other.Default = false
+	}
+	d.Default = true
+}
+
+func (s *Server) getDefaultAuthentication(w http.ResponseWriter, r *http.Request) {
+	domain := r.URL.Query().Get("domain")
+	for _, d := range s.domains {
+		if d.Default && (domain == "" || d.Domain == domain) {
+			writeJSON(w, http.StatusOK, d)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, struct{}{})
+}
+
+func (s *Server) getAuthenticatedDomain(w http.ResponseWriter, r *http.Request) {
+	if _, d := s.findDomain(w, r); d != nil {
+		writeJSON(w, http.StatusOK, d)
+	}
+}
+
+func (s *Server) updateDomainAuthentication(w http.ResponseWriter, r *http.Request) {
+	_, d := s.findDomain(w, r)
+	if d == nil {
+		return
+	}
+	var in sendgrid.InputUpdateDomainAuthentication
+	if !decode(w, r, &in) {
+		return
+	}
+	d.CustomSpf = in.CustomSpf
+	if in.Default {
+		s.setDefaultDomain(d)
+	} else {
+		d.Default = false
+	}
+	writeJSON(w, http.StatusOK, d)
+}
+
+func (s *Server) deleteAuthenticatedDomain(w http.ResponseWriter, r *http.Request) {
+	if i, d := s.findDomain(w, r); d != nil {
+		s.domains = slices.Delete(s.domains, i, i+1)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) validateDomainAuthentication(w http.ResponseWriter, r *http.Request) {
+	_, d := s.findDomain(w, r)
+	if d == nil {
+		return
+	}
+
+	result := func(rec sendgrid.Record) sendgrid.ValidationResult {
+		if rec.Valid {
+			return sendgrid.ValidationResult{Valid: true}
+		}
+		return sendgrid.ValidationResult{Reason: fmt.Sprintf("Expected CNAME for %q to match %q.", rec.Host, rec.Data)}
+	}
+	d.Valid = d.DNS.MailCname.Valid && d.DNS.Dkim1.Valid && d.DNS.Dkim2.Valid
+	d.LastValidationAttemptAt = s.now().Unix()
+	writeJSON(w, http.StatusOK, sendgrid.OutputValidateDomainAuthentication{
+		ID:    d.ID,
+		Valid: d.Valid,
+		ValidationResults: sendgrid.ValidationResults{
+			MailCname: result(d.DNS.MailCname),
+			Dkim1:     result(d.DNS.Dkim1),
+			Dkim2:     result(d.DNS.Dkim2),
+			SPF:       sendgrid.ValidationResult{Valid: true},
+		},
+	})
+}
+
+func (s *Server) addIPToAuthenticatedDomain(w http.ResponseWriter, r *http.Request) {
+	_, d := s.findDomain(w, r)
+	if d == nil {
+		return
+	}
+	var in sendgrid.InputAddIPToAuthenticatedDomain
+	if !decode(w, r, &in) {
+		return
+	}
+	if in.IP == "" {
+		writeError(w, http.StatusBadRequest, "ip", "ip is required")
+		return
+	}
+	if !slices.Contains(d.IPs, in.IP) {
+		d.IPs = append(d.IPs, in.IP)
+	}
+	writeJSON(w, http.StatusOK, d)
+}
+
+func (s *Server) removeIPFromAuthenticatedDomain(w http.ResponseWriter, r *http.Request) {
+	_, d := s.findDomain(w, r)
+	if d == nil {
+		return
+	}
+	i := slices.Index(d.IPs, r.PathValue("ip"))
+	if i < 0 {
+		writeNotFound(w, "ip")
+		return
+	}
+	d.IPs = slices.Delete(d.IPs, i, i+1)
+	writeJSON(w, http.StatusOK, d)
+}
+
+func (s *Server) findIPPool(w http.ResponseWriter, r *http.Request) (int, *ipPool) {
+	name := r.PathValue("name")
+	i := slices.IndexFunc(s.ipPools, func(p *ipPool) bool { return strings.EqualFold(p.Name, name) })
+	if i < 0 {
+		writeNotFound(w, "ip pool")
+		return -1, nil
+	}
+	return i, s.ipPools[i]
+}
+
+func (s *Server) getIPPools(w http.ResponseWriter, r *http.Request) {
+	pools := make([]sendgrid.IPPool, 0, len(s.ipPools))
+	for _, p := range s.ipPools {
+		pools = append(pools, sendgrid.IPPool{Name: p.Name})
+	}
+	writeJSON(w, http.StatusOK, pools)
+}
+
+func (s *Server) createIPPool(w http.ResponseWriter, r *http.Request) {
+	var in sendgrid.IPPool
+	if !decode(w, r, &in) {
+		return
+	}
+	if in.Name == "" {
+		writeError(w, http.StatusBadRequest, "name", "name is required")
+		return
+	}
+	if slices.ContainsFunc(s.ipPools, func(p *ipPool) bool { return strings.EqualFold(p.Name, in.Name) }) {
+		writeError(w, http.StatusBadRequest, "name", "an ip pool with this name already exists")
+		return
+	}
+	s.ipPools = append(s.ipPools, &ipPool{Name: in.Name})
+	writeJSON(w, http.StatusOK, in)
+}
+
+func (s *Server) getIPPool(w http.ResponseWriter, r *http.Request) {
+	if _, p := s.findIPPool(w, r); p != nil {
+		writeJSON(w, http.StatusOK, p)
+	}
+}
+
+func (s *Server) updateIPPool(w http.ResponseWriter, r *http.Request) {
+	_, p := s.findIPPool(w, r)
+	if p == nil {
+		return
+	}
+	var in sendgrid.IPPool
+	if !decode(w, r, &in) {
+		return
+	}
+	if in.Name == "" {
+		writeError(w, http.StatusBadRequest, "name", "name is required")
+		return
+	}
+	p.Name = in.Name
+	writeJSON(w, http.StatusOK, sendgrid.IPPool{Name: p.Name})
+}
+
+func (s *Server) deleteIPPool(w http.ResponseWriter, r *http.Request) {
+	if i, p := s.findIPPool(w, r); p != nil {
+		s.ipPools = slices.Delete(s.ipPools, i, i+1)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) addIPToPool(w http.ResponseWriter, r *http.Request) {
+	_, p := s.findIPPool(w, r)
+	if p == nil {
+		return
+	}
+	var in sendgrid.InputAddIPToPool
+	if !decode(w, r, &in) {
+		return
+	}
+	if in.IP == "" {
+		writeError(w, http.StatusBadRequest, "ip", "ip is required")
+		return
+	}
+	if !slices.Contains(p.IPs, in.IP) {
+		p.IPs = append(p.IPs, in.IP)
+	}
+	writeJSON(w, http.StatusCreated, sendgrid.IPAddress{IP: in.IP, Pools: []string{p.Name}})
+}
+
+func (s *Server) removeIPFromPool(w http.ResponseWriter, r *http.Request) {
+	_, p := s.findIPPool(w, r)
+	if p == nil {
+		return
+	}
+	i := slices.Index(p.IPs, r.PathValue("ip"))
+	if i < 0 {
+		writeNotFound(w, "ip")
+		return
+	}
+	p.IPs = slices.Delete(p.IPs, i, i+1)
+	w.WriteHeader(http.StatusNoContent)
+}
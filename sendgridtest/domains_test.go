@@ -0,0 +1,70 @@
+package sendgridtest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/i10416/sendgrid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthenticatedDomains(t *testing.T) {
+	fake, client := setup(t)
+	ctx := context.TODO()
+
+	created, err := client.AuthenticateDomain(ctx, &sendgrid.InputAuthenticateDomain{Domain: "example.com", Default: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "em.example.com", created.DNS.MailCname.Host)
+
+	validation, err := client.ValidateDomainAuthentication(ctx, created.ID)
+	assert.NoError(t, err)
+	assert.False(t, validation.Valid)
+	assert.NotEmpty(t, validation.ValidationResults.MailCname.Reason)
+
+	fake.SetDomainValid(created.ID, true)
+	validation, err = client.ValidateDomainAuthentication(ctx, created.ID)
+	assert.NoError(t, err)
+	assert.True(t, validation.Valid)
+
+	def, err := client.GetDefaultAuthentication(ctx, &sendgrid.InputGetDefaultAuthentication{Domain: "example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, created.ID, def.ID)
+
+	_, err = client.AddIPToAuthenticatedDomain(ctx, created.ID, &sendgrid.InputAddIPToAuthenticatedDomain{IP: "192.0.2.1"})
+	assert.NoError(t, err)
+	got, err := client.GetAuthenticatedDomain(ctx, created.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"192.0.2.1"}, got.IPs)
+	assert.True(t, got.Valid)
+
+	domains, err := client.GetAuthenticatedDomains(ctx, &sendgrid.InputGetAuthenticatedDomains{Domain: "example.com"})
+	assert.NoError(t, err)
+	assert.Len(t, domains, 1)
+
+	assert.NoError(t, client.DeleteAuthenticatedDomain(ctx, created.ID))
+	assert.Empty(t, fake.AuthenticatedDomains())
+}
+
+func TestIPPools(t *testing.T) {
+	fake, client := setup(t)
+	ctx := context.TODO()
+
+	_, err := client.CreateIPPool(ctx, "marketing")
+	assert.NoError(t, err)
+	assert.NoError(t, client.AddIPToPool(ctx, "marketing", "192.0.2.1"))
+	assert.NoError(t, client.AddIPToPool(ctx, "marketing", "192.0.2.2"))
+	assert.NoError(t, client.RemoveIPFromPool(ctx, "marketing", "192.0.2.1"))
+
+	renamed, err := client.UpdateIPPool(ctx, "marketing", "transactional")
+	assert.NoError(t, err)
+	assert.Equal(t, "transactional", renamed.Name)
+	assert.Equal(t, map[string][]string{"transactional": {"192.0.2.2"}}, fake.IPPools())
+
+	pools, err := client.GetIPPools(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []sendgrid.IPPool{{Name: "transactional"}}, pools)
+
+	assert.NoError(t, client.DeleteIPPool(ctx, "transactional"))
+	_, err = client.GetIPPool(ctx, "transactional")
+	assert.True(t, sendgrid.IsNotFound(err))
+}
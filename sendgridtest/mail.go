@@ -0,0 +1,194 @@
+package sendgridtest
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/i10416/sendgrid"
+)
+
+// Message is a mail accepted by the fake mail send endpoint.
+type Message struct {
+	// ID is the value returned in the X-Message-Id header.
+	ID string
+	// Mail is the decoded request body.
+	Mail *sendgrid.InputSendMail
+	// Header holds the request headers, e.g. On-Behalf-Of.
+	Header http.Header
+	// Sandbox reports whether the mail was sent in sandbox mode. SendGrid
+	// validates such mail but does not deliver it.
+	Sandbox bool
+}
+
+// Messages returns the mail accepted by the server in the order it was sent.
+func (s *Server) Messages() []*Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.messages)
+}
+
+// ScheduledSends returns the paused and canceled batches.
+func (s *Server) ScheduledSends() []sendgrid.ScheduledSend {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sends := make([]sendgrid.ScheduledSend, 0, len(s.scheduledSends))
+	for _, send := range s.scheduledSends {
+		sends = append(sends, *send)
+	}
+	return sends
+}
+
+func (s *Server) mailRoutes() {
+	s.handle("POST /mail/send", s.sendMail)
+	s.handle("POST /mail/batch", s.createBatchID)
+	s.handle("GET /mail/batch/{batch_id}", s.getBatchID)
+	s.handle("GET /user/scheduled_sends", s.getScheduledSends)
+	s.handle("POST /user/scheduled_sends", s.createScheduledSend)
+	s.handle("GET /user/scheduled_sends/{batch_id}", s.getScheduledSend)
+	s.handle("PATCH /user/scheduled_sends/{batch_id}", s.updateScheduledSend)
+	s.handle("DELETE /user/scheduled_sends/{batch_id}", s.deleteScheduledSend)
+}
+
+func (s *Server) sendMail(w http.ResponseWriter, r *http.Request) {
+	mail := new(sendgrid.InputSendMail)
+	if !decode(w, r, mail) {
+		return
+	}
+
+	if field, message := validateMail(mail); field != "" {
+		writeError(w, http.StatusBadRequest, field, message)
+		return
+	}
+
+	msg := &Message{
+		ID:     fmt.Sprintf("sendgridtest-%016d", s.id()),
+		Mail:   mail,
+		Header: r.Header.Clone(),
+	}
+	if mail.MailSettings != nil && mail.MailSettings.SandBoxMode != nil && mail.MailSettings.SandBoxMode.Enable != nil {
+		msg.Sandbox = *mail.MailSettings.SandBoxMode.Enable
+	}
+	s.messages = append(s.messages, msg)
+
+	w.Header().Set("X-Message-Id", msg.ID)
+	if msg.Sandbox {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// validateMail performs the basic checks SendGrid applies to mail send
+// requests. It returns the offending field and a message, or empty strings.
+func validateMail(mail *sendgrid.InputSendMail) (string, string) {
+	if len(mail.Personalizations) == 0 {
+		return "personalizations", "The personalizations field is required and must have at least one personalization."
+	}
+	for _, p := range mail.Personalizations {
+		if p == nil || len(p.To) == 0 {
+			return "personalizations.to", "The to array is required for all personalization objects, and must have at least one email object with a valid email address."
+		}
+	}
+	if mail.From == nil || mail.From.Email == "" {
+		return "from.email", "The from object must be provided for every email send. It is an object that requires the email parameter, but may also contain a name parameter."
+	}
+	if mail.TemplateID == "" {
+		if len(mail.Content) == 0 {
+			return "content", "Unless a valid template_id is provided, the content parameter is required."
+		}
+		if mail.Subject == "" {
+			for _, p := range mail.Personalizations {
+				if p.Subject == "" {
+					return "subject", "The subject is required. You can get around this requirement if you use a template with a subject defined or if every personalization has a subject defined."
+				}
+			}
+		}
+	}
+	return "", ""
+}
+
+func (s *Server) createBatchID(w http.ResponseWriter, r *http.Request) {
+	id := fmt.Sprintf("sendgridtest-batch-%016d", s.id())
+	s.batches = append(s.batches, id)
+	writeJSON(w, http.StatusCreated, sendgrid.OutputCreateBatchID{BatchID: id})
+}
+
+func (s *Server) getBatchID(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("batch_id")
+	if !slices.Contains(s.batches, id) {
+		writeError(w, http.StatusBadRequest, "", "invalid batch id")
+		return
+	}
+	writeJSON(w, http.StatusOK, sendgrid.OutputGetBatchID{BatchID: id})
+}
+
+func validScheduledSendStatus(status string) bool {
+	return status == sendgrid.ScheduledSendPause || status == sendgrid.ScheduledSendCancel
+}
+
+func (s *Server) findScheduledSend(w http.ResponseWriter, r *http.Request) (int, *sendgrid.ScheduledSend) {
+	id := r.PathValue("batch_id")
+	i := slices.IndexFunc(s.scheduledSends, func(send *sendgrid.ScheduledSend) bool { return send.BatchID == id })
+	if i < 0 {
+		writeNotFound(w, "scheduled send")
+		return -1, nil
+	}
+	return i, s.scheduledSends[i]
+}
+
+func (s *Server) getScheduledSends(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.scheduledSends)
+}
+
+func (s *Server) createScheduledSend(w http.ResponseWriter, r *http.Request) {
+	var in sendgrid.InputCreateScheduledSend
+	if !decode(w, r, &in) {
+		return
+	}
+	switch {
+	case !slices.Contains(s.batches, in.BatchID):
+		writeError(w, http.StatusBadRequest, "batch_id", "invalid batch id")
+		return
+	case !validScheduledSendStatus(in.Status):
+		writeError(w, http.StatusBadRequest, "status", "status must be either pause or cancel")
+		return
+	case slices.ContainsFunc(s.scheduledSends, func(send *sendgrid.ScheduledSend) bool { return send.BatchID == in.BatchID }):
+		writeError(w, http.StatusBadRequest, "batch_id", "batch id already has a status; update it instead")
+		return
+	}
+
+	send := &sendgrid.ScheduledSend{BatchID: in.BatchID, Status: in.Status}
+	s.scheduledSends = append(s.scheduledSends, send)
+	writeJSON(w, http.StatusCreated, send)
+}
+
+func (s *Server) getScheduledSend(w http.ResponseWriter, r *http.Request) {
+	if _, send := s.findScheduledSend(w, r); send != nil {
+		writeJSON(w, http.StatusOK, []*sendgrid.ScheduledSend{send})
+	}
+}
+
+func (s *Server) updateScheduledSend(w http.ResponseWriter, r *http.Request) {
+	_, send := s.findScheduledSend(w, r)
+	if send == nil {
+		return
+	}
+	var in sendgrid.InputUpdateScheduledSend
+	if !decode(w, r, &in) {
+		return
+	}
+	if !validScheduledSendStatus(in.Status) {
+		writeError(w, http.StatusBadRequest, "status", "status must be either pause or cancel")
+		return
+	}
+	send.Status = in.Status
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) deleteScheduledSend(w http.ResponseWriter, r *http.Request) {
+	if i, send := s.findScheduledSend(w, r); send != nil {
+		s.scheduledSends = slices.Delete(s.scheduledSends, i, i+1)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,106 @@
+package sendgridtest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/i10416/sendgrid"
+	"github.com/stretchr/testify/assert"
+)
+
+func testMail() *sendgrid.InputSendMail {
+	m := sendgrid.NewInputSendMail()
+	m.SetFrom(sendgrid.NewEmail("from@example.com", "From"))
+	m.SetSubject("Hello")
+	p := sendgrid.NewPersonalization()
+	p.AddTo(sendgrid.NewEmail("to@example.com", ""))
+	m.AddPersonalization(p)
+	m.AddContent(sendgrid.NewContent("text/plain", "Hello, world"))
+	return m
+}
+
+func TestSendMail(t *testing.T) {
+	fake, client := setup(t)
+
+	out, err := client.WithSubuser("acme").SendMail(context.TODO(), testMail())
+	assert.NoError(t, err)
+
+	msgs := fake.Messages()
+	if assert.Len(t, msgs, 1) {
+		assert.Equal(t, msgs[0].ID, out.MessageID)
+		assert.Equal(t, "Hello", msgs[0].Mail.Subject)
+		assert.Equal(t, "to@example.com", msgs[0].Mail.Personalizations[0].To[0].Email)
+		assert.Equal(t, "acme", msgs[0].Header.Get("On-Behalf-Of"))
+		assert.False(t, msgs[0].Sandbox)
+	}
+}
+
+func TestSendMailValidation(t *testing.T) {
+	fake, client := setup(t)
+
+	m := testMail()
+	m.From = nil
+	_, err := client.SendMail(context.TODO(), m)
+
+	var apiErr *sendgrid.APIError
+	if assert.ErrorAs(t, err, &apiErr) {
+		assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+		assert.Equal(t, "from.email", *apiErr.Errors[0].Field)
+	}
+
+	m = testMail()
+	m.Personalizations = []*sendgrid.Personalization{nil}
+	_, err = client.SendMail(context.TODO(), m)
+	if assert.ErrorAs(t, err, &apiErr) {
+		assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+		assert.Equal(t, "personalizations.to", *apiErr.Errors[0].Field)
+	}
+	assert.Empty(t, fake.Messages())
+}
+
+func TestSendMailSandbox(t *testing.T) {
+	fake, client := setup(t)
+
+	enable := true
+	m := testMail()
+	m.MailSettings = &sendgrid.MailSettings{SandBoxMode: &sendgrid.Setting{Enable: &enable}}
+	_, err := client.SendMail(context.TODO(), m)
+	assert.NoError(t, err)
+
+	msgs := fake.Messages()
+	if assert.Len(t, msgs, 1) {
+		assert.True(t, msgs[0].Sandbox)
+	}
+}
+
+func TestScheduleMail(t *testing.T) {
+	fake, client := setup(t)
+	ctx := context.TODO()
+
+	scheduled, err := client.ScheduleMail(ctx, testMail(), time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	_, err = client.GetBatchID(ctx, scheduled.BatchID)
+	assert.NoError(t, err)
+	if msgs := fake.Messages(); assert.Len(t, msgs, 1) {
+		assert.Equal(t, scheduled.BatchID, msgs[0].Mail.BatchID)
+		assert.Equal(t, scheduled.SendAt.Unix(), msgs[0].Mail.SendAt)
+	}
+
+	assert.NoError(t, scheduled.Pause(ctx))
+	assert.NoError(t, scheduled.Cancel(ctx))
+	assert.Equal(t, []sendgrid.ScheduledSend{{BatchID: scheduled.BatchID, Status: "cancel"}}, fake.ScheduledSends())
+
+	sends, err := client.GetScheduledSend(ctx, scheduled.BatchID)
+	assert.NoError(t, err)
+	assert.Equal(t, []*sendgrid.ScheduledSend{{BatchID: scheduled.BatchID, Status: "cancel"}}, sends)
+
+	assert.NoError(t, scheduled.Resume(ctx))
+	assert.Empty(t, fake.ScheduledSends())
+
+	_, err = client.GetBatchID(ctx, "unknown")
+	assert.Error(t, err)
+	_, err = client.CreateScheduledSend(ctx, &sendgrid.InputCreateScheduledSend{BatchID: "unknown", Status: "cancel"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,229 @@
+// Package sendgridtest provides utilities for testing code that uses the
+// sendgrid client: an in-memory fake of the SendGrid v3 API, a stand-in for
+// the SMTP relay, and a cassette that records and replays HTTP interactions.
+//
+//	fake := sendgridtest.NewServer()
+//	defer fake.Close()
+//
+//	c := sendgrid.New("test-key", sendgrid.OptionBaseURL(fake.URL))
+//	_, err := c.SendMail(ctx, mail)
+//	msgs := fake.Messages()
+//
+// The fake keeps state between calls, so a template created with
+// CreateTemplate can be retrieved with GetTemplate. It implements the
+// templates, template versions, suppressions, suppression groups, subusers,
+// teammates, event and inbound parse webhooks, authenticated domains, IP
+// pools, mail send and scheduled send endpoints covered by the client.
+//
+// SMTPServer accepts the mail of sendgrid.SMTPSender over STARTTLS and
+// records it, with its X-SMTPAPI header decoded.
+//
+// For endpoints the fake does not cover, a Cassette records the interactions
+// of a client with the real API and replays them offline.
+package sendgridtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/i10416/sendgrid"
+)
+
+const basePath = "/v3"
+
+// Server is an in-memory fake of the SendGrid v3 API.
+type Server struct {
+	// URL is the base URL of the fake API, including the /v3 path prefix.
+	// Pass it to sendgrid.OptionBaseURL.
+	URL string
+
+	server *httptest.Server
+	mux    *http.ServeMux
+
+	mu     sync.Mutex
+	nextID int64
+	now    func() time.Time
+
+	messages       []*Message
+	batches        []string
+	scheduledSends []*sendgrid.ScheduledSend
+
+	templates []*template
+
+	bounces       *suppressionList[sendgrid.Bounce]
+	blocks        *suppressionList[sendgrid.Block]
+	spamReports   *suppressionList[sendgrid.SpamReport]
+	invalidEmails *suppressionList[sendgrid.InvalidEmail]
+	groups        []*sendgrid.SuppressionGroup
+	// groupSuppressions holds the suppressed emails of each group, by ID.
+	groupSuppressions map[int64][]string
+
+	subusers         []*subuser
+	teammates        []*sendgrid.OutputGetTeammate
+	pendingTeammates []*sendgrid.PendingTeammate
+
+	eventWebhooks []*eventWebhook
+	parseWebhooks []*sendgrid.InboundParseWebhook
+
+	domains []*sendgrid.DomainAuthentication
+	ipPools []*ipPool
+}
+
+// NewServer starts and returns a new fake server. The caller should call
+// Close when finished, to shut it down.
+func NewServer() *Server {
+	s := &Server{
+		mux: http.NewServeMux(),
+		now: time.Now,
+
+		bounces:       newSuppressionList(func(b sendgrid.Bounce) (string, int64) { return b.Email, b.Created }),
+		blocks:        newSuppressionList(func(b sendgrid.Block) (string, int64) { return b.Email, b.Created }),
+		spamReports:   newSuppressionList(func(r sendgrid.SpamReport) (string, int64) { return r.Email, r.Created }),
+		invalidEmails: newSuppressionList(func(e sendgrid.InvalidEmail) (string, int64) { return e.Email, e.Created }),
+	}
+	s.reset()
+	s.routes()
+
+	s.server = httptest.NewServer(s)
+	s.URL = s.server.URL + basePath
+	return s
+}
+
+// Close shuts down the server.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Reset discards all state stored in the server.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reset()
+}
+
+func (s *Server) reset() {
+	s.nextID = 0
+	s.messages = nil
+	s.batches = nil
+	s.scheduledSends = nil
+	s.templates = nil
+	s.bounces.items = nil
+	s.blocks.items = nil
+	s.spamReports.items = nil
+	s.invalidEmails.items = nil
+	s.groups = nil
+	s.groupSuppressions = map[int64][]string{}
+	s.subusers = nil
+	s.teammates = nil
+	s.pendingTeammates = nil
+	s.eventWebhooks = nil
+	s.parseWebhooks = nil
+	s.domains = nil
+	s.ipPools = nil
+}
+
+// ServeHTTP implements http.Handler. It rejects requests without a bearer
+// token and serializes access to the stored state.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") || len(r.Header.Get("Authorization")) == len("Bearer ") {
+		writeError(w, http.StatusUnauthorized, "", "authorization required")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) handle(pattern string, h http.HandlerFunc) {
+	method, path, _ := strings.Cut(pattern, " ")
+	s.mux.HandleFunc(method+" "+basePath+path, h)
+}
+
+func (s *Server) routes() {
+	s.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "", fmt.Sprintf("sendgridtest: %s %s is not implemented", r.Method, r.URL.Path))
+	})
+
+	s.mailRoutes()
+	s.templateRoutes()
+	s.suppressionRoutes()
+	s.accountRoutes()
+	s.webhookRoutes()
+	s.domainRoutes()
+}
+
+func (s *Server) id() int64 {
+	s.nextID++
+	return s.nextID
+}
+
+func (s *Server) timestamp() string {
+	return s.now().UTC().Format("2006-01-02 15:04:05")
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if v != nil {
+		_ = json.NewEncoder(w).Encode(v)
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, field, message string) {
+	e := &sendgrid.Error{Message: &message}
+	if field != "" {
+		e.Field = &field
+	}
+	writeJSON(w, code, sendgrid.ErrorsResponse{Errors: []*sendgrid.Error{e}})
+}
+
+func writeNotFound(w http.ResponseWriter, what string) {
+	writeError(w, http.StatusNotFound, "", what+" not found")
+}
+
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "", "invalid JSON body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// page applies limit and offset query parameters to items. If either is not
+// a non-negative integer, it writes a 400 response and returns false.
+func page[T any](w http.ResponseWriter, r *http.Request, items []T) ([]T, bool) {
+	q := r.URL.Query()
+	var n [2]int
+	for i, name := range []string{"offset", "limit"} {
+		v := q.Get(name)
+		if v == "" {
+			continue
+		}
+		var err error
+		if n[i], err = strconv.Atoi(v); err != nil || n[i] < 0 {
+			writeError(w, http.StatusBadRequest, name, name+" must be a non-negative integer")
+			return nil, false
+		}
+	}
+	offset, limit := n[0], n[1]
+	if offset >= len(items) {
+		return []T{}, true
+	}
+	items = items[offset:]
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items, true
+}
+
+func pathInt(r *http.Request, name string) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue(name), 10, 64)
+	return id, err == nil
+}
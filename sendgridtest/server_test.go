@@ -0,0 +1,53 @@
+package sendgridtest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/i10416/sendgrid"
+	"github.com/stretchr/testify/assert"
+)
+
+// setup starts a fake server along with a sendgrid.Client that talks to it.
+func setup(t *testing.T) (*Server, *sendgrid.Client) {
+	t.Helper()
+	fake := NewServer()
+	t.Cleanup(fake.Close)
+	return fake, sendgrid.New("test-token", sendgrid.OptionBaseURL(fake.URL))
+}
+
+func TestServerUnauthorized(t *testing.T) {
+	fake, _ := setup(t)
+
+	client := sendgrid.New("", sendgrid.OptionBaseURL(fake.URL))
+	_, err := client.GetTemplates(context.TODO(), &sendgrid.InputGetTemplates{})
+	assert.True(t, sendgrid.IsUnauthorized(err))
+}
+
+func TestServerNotImplemented(t *testing.T) {
+	_, client := setup(t)
+
+	_, err := client.GetAlerts(context.TODO())
+	var apiErr *sendgrid.APIError
+	if assert.ErrorAs(t, err, &apiErr) {
+		assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+		assert.Contains(t, apiErr.Error(), "not implemented")
+	}
+}
+
+func TestServerReset(t *testing.T) {
+	fake, client := setup(t)
+
+	_, err := client.CreateTemplate(context.TODO(), &sendgrid.InputCreateTemplate{Name: "welcome", Generation: "dynamic"})
+	assert.NoError(t, err)
+	fake.AddBounce(sendgrid.Bounce{Email: "a@example.com"})
+
+	fake.Reset()
+	assert.Empty(t, fake.Templates())
+	assert.Empty(t, fake.Bounces())
+
+	bounces, err := client.GetBounces(context.TODO(), nil)
+	assert.NoError(t, err)
+	assert.Empty(t, bounces)
+}
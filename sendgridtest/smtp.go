@@ -0,0 +1,341 @@
+package sendgridtest
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/i10416/sendgrid"
+)
+
+// SMTPServer is a local stand-in for the SendGrid SMTP relay, for testing
+// sendgrid.SMTPSender. It supports EHLO, STARTTLS with a self-signed
+// certificate, AUTH PLAIN with the apikey username, and records the
+// messages it receives.
+type SMTPServer struct {
+	// Addr is the host:port the server listens on.
+	Addr string
+
+	ln     net.Listener
+	config *tls.Config
+	roots  *x509.CertPool
+	wg     sync.WaitGroup
+
+	mu       sync.Mutex
+	closed   bool
+	conns    map[net.Conn]bool
+	messages []*SMTPMessage
+	nextID   int
+}
+
+// SMTPMessage is a message received by SMTPServer.
+type SMTPMessage struct {
+	// ID is the ID reported in the "250 Ok: queued as <id>" reply.
+	ID string
+	// APIKey is the password the client authenticated with.
+	APIKey string
+	// From and To are the envelope sender and recipients.
+	From string
+	To   []string
+	// Data is the message as received, with line endings converted to \n.
+	Data []byte
+	// SMTPAPI is the decoded X-SMTPAPI header, or nil if there is none.
+	SMTPAPI *sendgrid.SMTPAPIHeader
+	// Mail is Data parsed with sendgrid.ParseEML, or nil if it does not
+	// parse.
+	Mail *sendgrid.InputSendMail
+}
+
+// NewSMTPServer starts and returns a new SMTP stand-in listening on a local
+// port. The caller should call Close when finished, to shut it down.
+func NewSMTPServer() *SMTPServer {
+	cert, roots, err := selfSignedCert()
+	if err != nil {
+		panic(fmt.Sprintf("sendgridtest: failed to create certificate: %v", err))
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("sendgridtest: failed to listen: %v", err))
+	}
+
+	s := &SMTPServer{
+		Addr:   ln.Addr().String(),
+		ln:     ln,
+		config: &tls.Config{Certificates: []tls.Certificate{cert}},
+		roots:  roots,
+		conns:  map[net.Conn]bool{},
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s
+}
+
+// Close shuts down the server and its open connections.
+func (s *SMTPServer) Close() {
+	s.ln.Close()
+	s.mu.Lock()
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// ClientTLSConfig returns a TLS configuration that trusts the certificate
+// of the server, for sendgrid.SMTPSender.TLSConfig.
+func (s *SMTPServer) ClientTLSConfig() *tls.Config {
+	return &tls.Config{RootCAs: s.roots}
+}
+
+// Messages returns the received messages.
+func (s *SMTPServer) Messages() []*SMTPMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.messages)
+}
+
+// Reset discards the received messages.
+func (s *SMTPServer) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = nil
+	s.nextID = 0
+}
+
+func (s *SMTPServer) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = true
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+			conn.Close()
+		}()
+	}
+}
+
+// smtpSession is the state of an SMTP connection.
+type smtpSession struct {
+	tls    bool
+	apiKey string
+	from   string
+	to     []string
+}
+
+func (s *SMTPServer) handle(conn net.Conn) {
+	tc := textproto.NewConn(conn)
+	var sess smtpSession
+	reply := func(format string, args ...interface{}) bool {
+		return tc.PrintfLine(format, args...) == nil
+	}
+	if !reply("220 sendgridtest ESMTP") {
+		return
+	}
+
+	for {
+		line, err := tc.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		ok := true
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			sess.from, sess.to = "", nil
+			if sess.tls {
+				ok = reply("250-sendgridtest") && reply("250-AUTH PLAIN") && reply("250 8BITMIME")
+			} else {
+				ok = reply("250-sendgridtest") && reply("250-STARTTLS") && reply("250 8BITMIME")
+			}
+		case "STARTTLS":
+			if sess.tls {
+				ok = reply("503 TLS already active")
+				break
+			}
+			if !reply("220 Ready to start TLS") {
+				return
+			}
+			tlsConn := tls.Server(conn, s.config)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			tc = textproto.NewConn(tlsConn)
+			sess = smtpSession{tls: true}
+		case "AUTH":
+			ok = s.auth(&sess, arg, reply)
+		case "MAIL":
+			addr, valid := smtpPath(arg, "FROM:")
+			switch {
+			case sess.apiKey == "":
+				ok = reply("530 Authentication required")
+			case !valid:
+				ok = reply("501 Syntax error in MAIL FROM")
+			default:
+				sess.from, sess.to = addr, nil
+				ok = reply("250 Ok")
+			}
+		case "RCPT":
+			addr, valid := smtpPath(arg, "TO:")
+			switch {
+			case sess.from == "":
+				ok = reply("503 Need MAIL before RCPT")
+			case !valid || addr == "":
+				ok = reply("501 Syntax error in RCPT TO")
+			default:
+				sess.to = append(sess.to, addr)
+				ok = reply("250 Ok")
+			}
+		case "DATA":
+			if len(sess.to) == 0 {
+				ok = reply("503 Need RCPT before DATA")
+				break
+			}
+			if !reply("354 End data with <CR><LF>.<CR><LF>") {
+				return
+			}
+			data, err := tc.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg := s.record(&sess, data)
+			sess.from, sess.to = "", nil
+			ok = reply("250 Ok: queued as %s", msg.ID)
+		case "RSET":
+			sess.from, sess.to = "", nil
+			ok = reply("250 Ok")
+		case "NOOP":
+			ok = reply("250 Ok")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			ok = reply("502 Command not implemented")
+		}
+		if !ok {
+			return
+		}
+	}
+}
+
+func (s *SMTPServer) auth(sess *smtpSession, arg string, reply func(string, ...interface{}) bool) bool {
+	if !sess.tls {
+		return reply("530 Must issue STARTTLS first")
+	}
+	mechanism, initial, _ := strings.Cut(arg, " ")
+	if !strings.EqualFold(mechanism, "PLAIN") {
+		return reply("504 Unrecognized authentication type")
+	}
+	b, err := base64.StdEncoding.DecodeString(initial)
+	if err != nil {
+		return reply("501 Invalid base64 data")
+	}
+	fields := strings.Split(string(b), "\x00")
+	if len(fields) != 3 || fields[1] != "apikey" || fields[2] == "" {
+		return reply("535 Authentication failed")
+	}
+	sess.apiKey = fields[2]
+	return reply("235 Authentication successful")
+}
+
+func (s *SMTPServer) record(sess *smtpSession, data []byte) *SMTPMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	msg := &SMTPMessage{
+		ID:     fmt.Sprintf("sendgridtest-smtp-%016d", s.nextID),
+		APIKey: sess.apiKey,
+		From:   sess.from,
+		To:     slices.Clone(sess.to),
+		Data:   data,
+	}
+	if m, err := mail.ReadMessage(bytes.NewReader(data)); err == nil {
+		if v := m.Header.Get("X-SMTPAPI"); v != "" {
+			var api sendgrid.SMTPAPIHeader
+			if json.Unmarshal([]byte(v), &api) == nil {
+				msg.SMTPAPI = &api
+			}
+		}
+	}
+	if m, err := sendgrid.ParseEML(bytes.NewReader(data)); err == nil {
+		msg.Mail = m
+	}
+	s.messages = append(s.messages, msg)
+	return msg
+}
+
+// smtpPath returns the address of a MAIL FROM or RCPT TO argument such as
+// "FROM:<a@example.com> BODY=8BITMIME".
+func smtpPath(arg, prefix string) (string, bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", false
+	}
+	path, _, _ := strings.Cut(strings.TrimSpace(arg[len(prefix):]), " ")
+	if !strings.HasPrefix(path, "<") || !strings.HasSuffix(path, ">") {
+		return "", false
+	}
+	return path[1 : len(path)-1], true
+}
+
+// selfSignedCert returns a certificate for 127.0.0.1 and localhost, and a
+// pool that trusts it.
+func selfSignedCert() (tls.Certificate, *x509.CertPool, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{Organization: []string{"sendgridtest"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		DNSNames:              []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(leaf)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, roots, nil
+}
@@ -0,0 +1,103 @@
+package sendgridtest
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/i10416/sendgrid"
+	"github.com/stretchr/testify/assert"
+)
+
+func smtpSetup(t *testing.T) (*SMTPServer, *sendgrid.SMTPSender) {
+	t.Helper()
+	fake := NewSMTPServer()
+	t.Cleanup(fake.Close)
+	sender := sendgrid.NewSMTPSender("test-token")
+	sender.Addr = fake.Addr
+	sender.TLSConfig = fake.ClientTLSConfig()
+	return fake, sender
+}
+
+func TestSMTPSender(t *testing.T) {
+	fake, sender := smtpSetup(t)
+
+	m := sendgrid.NewInputSendMail()
+	m.SetFrom(sendgrid.NewEmail("from@example.com", "Sender"))
+	m.SetSubject("Hello -name-")
+	m.AddContent(sendgrid.NewContent("text/plain", "Hi -name-"))
+	m.Categories = []string{"welcome"}
+	m.CustomArgs = map[string]string{"campaign": "spring"}
+	m.ASM = &sendgrid.ASM{GroupID: 42}
+	for _, name := range []string{"alice", "bob"} {
+		p := sendgrid.NewPersonalization()
+		p.AddTo(sendgrid.NewEmail(name+"@example.com", ""))
+		p.Substitutions = map[string]string{"-name-": name}
+		p.CustomArgs = map[string]string{"user": name}
+		m.AddPersonalization(p)
+	}
+	m.Personalizations[1].Bcc = []*sendgrid.Email{sendgrid.NewEmail("audit@example.com", "")}
+
+	var s sendgrid.Sender = sender
+	out, err := s.SendMail(context.TODO(), m)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	msgs := fake.Messages()
+	if !assert.Len(t, msgs, 2) {
+		return
+	}
+	assert.Equal(t, msgs[0].ID, out.MessageID)
+	assert.Equal(t, "test-token", msgs[0].APIKey)
+	assert.Equal(t, "from@example.com", msgs[0].From)
+	assert.Equal(t, []string{"bob@example.com", "audit@example.com"}, msgs[1].To)
+
+	if assert.NotNil(t, msgs[1].SMTPAPI) {
+		assert.Equal(t, &sendgrid.SMTPAPIHeader{
+			UniqueArgs: map[string]string{"campaign": "spring", "user": "bob"},
+			Category:   []string{"welcome"},
+			ASMGroupID: 42,
+		}, msgs[1].SMTPAPI)
+	}
+	if assert.NotNil(t, msgs[1].Mail) {
+		assert.Equal(t, "Hello bob", msgs[1].Mail.Subject)
+		assert.Equal(t, "Hi bob", strings.TrimSpace(msgs[1].Mail.Content[0].Value))
+		assert.Empty(t, msgs[1].Mail.Personalizations[0].Bcc)
+	}
+}
+
+func TestSMTPSender_AuthFailed(t *testing.T) {
+	fake, _ := smtpSetup(t)
+
+	sender := sendgrid.NewSMTPSender("")
+	sender.Addr = fake.Addr
+	sender.TLSConfig = fake.ClientTLSConfig()
+
+	m := sendgrid.NewInputSendMail()
+	m.SetFrom(sendgrid.NewEmail("from@example.com", ""))
+	m.AddContent(sendgrid.NewContent("text/plain", "Hi"))
+	p := sendgrid.NewPersonalization()
+	p.AddTo(sendgrid.NewEmail("to@example.com", ""))
+	m.AddPersonalization(p)
+
+	_, err := sender.SendMail(context.TODO(), m)
+	assert.ErrorContains(t, err, "535")
+	assert.Empty(t, fake.Messages())
+}
+
+func TestSMTPSender_UntrustedCertificate(t *testing.T) {
+	fake, sender := smtpSetup(t)
+	sender.TLSConfig = nil
+
+	m := sendgrid.NewInputSendMail()
+	m.SetFrom(sendgrid.NewEmail("from@example.com", ""))
+	m.AddContent(sendgrid.NewContent("text/plain", "Hi"))
+	p := sendgrid.NewPersonalization()
+	p.AddTo(sendgrid.NewEmail("to@example.com", ""))
+	m.AddPersonalization(p)
+
+	_, err := sender.SendMail(context.TODO(), m)
+	assert.Error(t, err)
+	assert.Empty(t, fake.Messages())
+}
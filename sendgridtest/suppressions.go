@@ -0,0 +1,352 @@
+package sendgridtest
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/i10416/sendgrid"
+)
+
+// suppressionList stores the entries of one global suppression list, keyed
+// by email address.
+type suppressionList[T any] struct {
+	items []T
+	key   func(T) (email string, created int64)
+}
+
+func newSuppressionList[T any](key func(T) (string, int64)) *suppressionList[T] {
+	return &suppressionList[T]{key: key}
+}
+
+func (l *suppressionList[T]) email(item T) string {
+	email, _ := l.key(item)
+	return strings.ToLower(email)
+}
+
+func (l *suppressionList[T]) index(email string) int {
+	email = strings.ToLower(email)
+	return slices.IndexFunc(l.items, func(item T) bool { return l.email(item) == email })
+}
+
+// add stores item, replacing any entry for the same email.
+func (l *suppressionList[T]) add(item T) {
+	if i := l.index(l.email(item)); i >= 0 {
+		l.items[i] = item
+		return
+	}
+	l.items = append(l.items, item)
+}
+
+func (l *suppressionList[T]) remove(email string) bool {
+	i := l.index(email)
+	if i < 0 {
+		return false
+	}
+	l.items = slices.Delete(l.items, i, i+1)
+	return true
+}
+
+// list applies the start_time, end_time, email, limit and offset query
+// parameters of r to the stored entries. If they are invalid, it writes a
+// 400 response and returns false.
+func (l *suppressionList[T]) list(w http.ResponseWriter, r *http.Request) ([]T, bool) {
+	q := r.URL.Query()
+	start, _ := strconv.ParseInt(q.Get("start_time"), 10, 64)
+	end, _ := strconv.ParseInt(q.Get("end_time"), 10, 64)
+	email := strings.ToLower(q.Get("email"))
+
+	matched := []T{}
+	for _, item := range l.items {
+		e, created := l.key(item)
+		if start > 0 && created < start || end > 0 && created > end {
+			continue
+		}
+		if email != "" && !strings.Contains(strings.ToLower(e), email) {
+			continue
+		}
+		matched = append(matched, item)
+	}
+	return page(w, r, matched)
+}
+
+func (l *suppressionList[T]) routes(s *Server, path string) {
+	s.handle("GET "+path, func(w http.ResponseWriter, r *http.Request) {
+		list, ok := l.list(w, r)
+		if !ok {
+			return
+		}
+		writeJSON(w, http.StatusOK, list)
+	})
+	s.handle("GET "+path+"/{email}", func(w http.ResponseWriter, r *http.Request) {
+		found := []T{}
+		if i := l.index(r.PathValue("email")); i >= 0 {
+			found = append(found, l.items[i])
+		}
+		writeJSON(w, http.StatusOK, found)
+	})
+	s.handle("DELETE "+path, func(w http.ResponseWriter, r *http.Request) {
+		var in sendgrid.InputDeleteSuppressions
+		if !decode(w, r, &in) {
+			return
+		}
+		switch {
+		case in.DeleteAll:
+			l.items = nil
+		case len(in.Emails) > 0:
+			for _, email := range in.Emails {
+				l.remove(email)
+			}
+		default:
+			writeError(w, http.StatusBadRequest, "", "either delete_all or emails is required")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	s.handle("DELETE "+path+"/{email}", func(w http.ResponseWriter, r *http.Request) {
+		if !l.remove(r.PathValue("email")) {
+			writeNotFound(w, "email")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func (s *Server) suppressionRoutes() {
+	s.bounces.routes(s, "/suppression/bounces")
+	s.blocks.routes(s, "/suppression/blocks")
+	s.spamReports.routes(s, "/suppression/spam_reports")
+	s.invalidEmails.routes(s, "/suppression/invalid_emails")
+
+	s.handle("GET /asm/groups", s.getSuppressionGroups)
+	s.handle("POST /asm/groups", s.createSuppressionGroup)
+	s.handle("GET /asm/groups/{id}", s.getSuppressionGroup)
+	s.handle("PATCH /asm/groups/{id}", s.updateSuppressionGroup)
+	s.handle("DELETE /asm/groups/{id}", s.deleteSuppressionGroup)
+	s.handle("GET /asm/groups/{id}/suppressions", s.getGroupSuppressions)
+	s.handle("POST /asm/groups/{id}/suppressions", s.addGroupSuppressions)
+	s.handle("DELETE /asm/groups/{id}/suppressions/{email}", s.deleteGroupSuppression)
+}
+
+// AddBounce stores a bounce. Created defaults to the current time.
+func (s *Server) AddBounce(b sendgrid.Bounce) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if b.Created == 0 {
+		b.Created = s.now().Unix()
+	}
+	s.bounces.add(b)
+}
+
+// Bounces returns the stored bounces.
+func (s *Server) Bounces() []sendgrid.Bounce {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.bounces.items)
+}
+
+// AddBlock stores a block. Created defaults to the current time.
+func (s *Server) AddBlock(b sendgrid.Block) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if b.Created == 0 {
+		b.Created = s.now().Unix()
+	}
+	s.blocks.add(b)
+}
+
+// Blocks returns the stored blocks.
+func (s *Server) Blocks() []sendgrid.Block {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.blocks.items)
+}
+
+// AddSpamReport stores a spam report. Created defaults to the current time.
+func (s *Server) AddSpamReport(r sendgrid.SpamReport) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.Created == 0 {
+		r.Created = s.now().Unix()
+	}
+	s.spamReports.add(r)
+}
+
+// SpamReports returns the stored spam reports.
+func (s *Server) SpamReports() []sendgrid.SpamReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.spamReports.items)
+}
+
+// AddInvalidEmail stores an invalid email. Created defaults to the current
+// time.
+func (s *Server) AddInvalidEmail(e sendgrid.InvalidEmail) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e.Created == 0 {
+		e.Created = s.now().Unix()
+	}
+	s.invalidEmails.add(e)
+}
+
+// InvalidEmails returns the stored invalid emails.
+func (s *Server) InvalidEmails() []sendgrid.InvalidEmail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.invalidEmails.items)
+}
+
+// SuppressionGroups returns the stored suppression groups.
+func (s *Server) SuppressionGroups() []sendgrid.SuppressionGroup {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	groups := make([]sendgrid.SuppressionGroup, 0, len(s.groups))
+	for _, g := range s.groups {
+		groups = append(groups, *g)
+	}
+	return groups
+}
+
+// GroupSuppressions returns the emails suppressed in the suppression group
+// id.
+func (s *Server) GroupSuppressions(id int64) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.groupSuppressions[id])
+}
+
+func (s *Server) findSuppressionGroup(w http.ResponseWriter, r *http.Request) (int, *sendgrid.SuppressionGroup) {
+	id, ok := pathInt(r, "id")
+	i := slices.IndexFunc(s.groups, func(g *sendgrid.SuppressionGroup) bool { return g.ID == id })
+	if !ok || i < 0 {
+		writeNotFound(w, "suppression group")
+		return -1, nil
+	}
+	return i, s.groups[i]
+}
+
+// setDefaultGroup makes g the only default group.
+func (s *Server) setDefaultGroup(g *sendgrid.SuppressionGroup) {
+	for _, other := range s.groups {
+		other.IsDefault = false
+	}
+	g.IsDefault = true
+}
+
+func (s *Server) getSuppressionGroups(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.groups)
+}
+
+func (s *Server) createSuppressionGroup(w http.ResponseWriter, r *http.Request) {
+	var in sendgrid.InputCreateSuppressionGroup
+	if !decode(w, r, &in) {
+		return
+	}
+	if in.Name == "" {
+		writeError(w, http.StatusBadRequest, "name", "name is required")
+		return
+	}
+	if in.Description == "" {
+		writeError(w, http.StatusBadRequest, "description", "description is required")
+		return
+	}
+
+	g := &sendgrid.SuppressionGroup{
+		ID:          s.id(),
+		Name:        in.Name,
+		Description: in.Description,
+	}
+	s.groups = append(s.groups, g)
+	if in.IsDefault {
+		s.setDefaultGroup(g)
+	}
+	writeJSON(w, http.StatusCreated, g)
+}
+
+func (s *Server) getSuppressionGroup(w http.ResponseWriter, r *http.Request) {
+	if _, g := s.findSuppressionGroup(w, r); g != nil {
+		writeJSON(w, http.StatusOK, g)
+	}
+}
+
+func (s *Server) updateSuppressionGroup(w http.ResponseWriter, r *http.Request) {
+	_, g := s.findSuppressionGroup(w, r)
+	if g == nil {
+		return
+	}
+	var in sendgrid.InputUpdateSuppressionGroup
+	if !decode(w, r, &in) {
+		return
+	}
+	if in.Name != "" {
+		g.Name = in.Name
+	}
+	if in.Description != "" {
+		g.Description = in.Description
+	}
+	if in.IsDefault {
+		s.setDefaultGroup(g)
+	} else {
+		g.IsDefault = false
+	}
+	writeJSON(w, http.StatusCreated, g)
+}
+
+func (s *Server) deleteSuppressionGroup(w http.ResponseWriter, r *http.Request) {
+	if i, g := s.findSuppressionGroup(w, r); g != nil {
+		s.groups = slices.Delete(s.groups, i, i+1)
+		delete(s.groupSuppressions, g.ID)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) getGroupSuppressions(w http.ResponseWriter, r *http.Request) {
+	if _, g := s.findSuppressionGroup(w, r); g != nil {
+		emails := s.groupSuppressions[g.ID]
+		if emails == nil {
+			emails = []string{}
+		}
+		writeJSON(w, http.StatusOK, emails)
+	}
+}
+
+func (s *Server) addGroupSuppressions(w http.ResponseWriter, r *http.Request) {
+	_, g := s.findSuppressionGroup(w, r)
+	if g == nil {
+		return
+	}
+	var in sendgrid.InputAddSuppressionsToGroup
+	if !decode(w, r, &in) {
+		return
+	}
+	if len(in.RecipientEmails) == 0 {
+		writeError(w, http.StatusBadRequest, "recipient_emails", "recipient_emails is required")
+		return
+	}
+	for _, email := range in.RecipientEmails {
+		email = strings.ToLower(email)
+		if !slices.Contains(s.groupSuppressions[g.ID], email) {
+			s.groupSuppressions[g.ID] = append(s.groupSuppressions[g.ID], email)
+		}
+	}
+	g.Unsubscribes = int64(len(s.groupSuppressions[g.ID]))
+	writeJSON(w, http.StatusCreated, sendgrid.OutputAddSuppressionsToGroup{RecipientEmails: in.RecipientEmails})
+}
+
+func (s *Server) deleteGroupSuppression(w http.ResponseWriter, r *http.Request) {
+	_, g := s.findSuppressionGroup(w, r)
+	if g == nil {
+		return
+	}
+	email := strings.ToLower(r.PathValue("email"))
+	i := slices.Index(s.groupSuppressions[g.ID], email)
+	if i < 0 {
+		writeNotFound(w, "email")
+		return
+	}
+	s.groupSuppressions[g.ID] = slices.Delete(s.groupSuppressions[g.ID], i, i+1)
+	g.Unsubscribes = int64(len(s.groupSuppressions[g.ID]))
+	w.WriteHeader(http.StatusNoContent)
+}
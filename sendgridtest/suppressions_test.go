@@ -0,0 +1,138 @@
+package sendgridtest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/i10416/sendgrid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBounces(t *testing.T) {
+	fake, client := setup(t)
+	ctx := context.TODO()
+
+	fake.AddBounce(sendgrid.Bounce{Email: "a@example.com", Created: 100, Reason: "550"})
+	fake.AddBounce(sendgrid.Bounce{Email: "b@example.com", Created: 200})
+	fake.AddBounce(sendgrid.Bounce{Email: "c@example.com", Created: 300})
+
+	bounces, err := client.GetBounces(ctx, &sendgrid.SuppressionListOptions{StartTime: 150})
+	assert.NoError(t, err)
+	assert.Len(t, bounces, 2)
+
+	bounces, err = client.GetBounces(ctx, &sendgrid.SuppressionListOptions{Limit: 1, Offset: 1})
+	assert.NoError(t, err)
+	if assert.Len(t, bounces, 1) {
+		assert.Equal(t, "b@example.com", bounces[0].Email)
+	}
+
+	_, err = client.GetBounces(ctx, &sendgrid.SuppressionListOptions{Offset: -1})
+	var apiErr *sendgrid.APIError
+	if assert.ErrorAs(t, err, &apiErr) {
+		assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+		assert.Equal(t, "offset", *apiErr.Errors[0].Field)
+	}
+
+	bounce, err := client.GetBounce(ctx, "a@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "550", bounce.Reason)
+
+	assert.NoError(t, client.DeleteBounce(ctx, "a@example.com"))
+	assert.NoError(t, client.DeleteBounces(ctx, &sendgrid.InputDeleteSuppressions{Emails: []string{"b@example.com"}}))
+	assert.Equal(t, []sendgrid.Bounce{{Email: "c@example.com", Created: 300}}, fake.Bounces())
+
+	err = client.DeleteBounce(ctx, "a@example.com")
+	assert.True(t, sendgrid.IsNotFound(err))
+
+	assert.NoError(t, client.DeleteBounces(ctx, &sendgrid.InputDeleteSuppressions{DeleteAll: true}))
+	assert.Empty(t, fake.Bounces())
+}
+
+func TestSuppressionLists(t *testing.T) {
+	fake, client := setup(t)
+	ctx := context.TODO()
+
+	fake.AddBlock(sendgrid.Block{Email: "block@example.com"})
+	fake.AddSpamReport(sendgrid.SpamReport{Email: "spam@example.com"})
+	fake.AddInvalidEmail(sendgrid.InvalidEmail{Email: "invalid@example.com"})
+
+	blocks, err := client.GetBlocks(ctx, nil)
+	assert.NoError(t, err)
+	assert.Len(t, blocks, 1)
+	assert.NotZero(t, blocks[0].Created)
+
+	report, err := client.GetSpamReport(ctx, "spam@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "spam@example.com", report.Email)
+
+	assert.NoError(t, client.DeleteInvalidEmail(ctx, "invalid@example.com"))
+	assert.Empty(t, fake.InvalidEmails())
+}
+
+func TestSuppressionGroups(t *testing.T) {
+	fake, client := setup(t)
+	ctx := context.TODO()
+
+	a, err := client.CreateSuppressionGroup(ctx, &sendgrid.InputCreateSuppressionGroup{Name: "news", Description: "Newsletter", IsDefault: true})
+	assert.NoError(t, err)
+	b, err := client.CreateSuppressionGroup(ctx, &sendgrid.InputCreateSuppressionGroup{Name: "promo", Description: "Promotions"})
+	assert.NoError(t, err)
+
+	_, err = client.UpdateSuppressionGroup(ctx, b.ID, &sendgrid.InputUpdateSuppressionGroup{IsDefault: true})
+	assert.NoError(t, err)
+
+	got, err := client.GetSuppressionGroup(ctx, a.ID)
+	assert.NoError(t, err)
+	assert.False(t, got.IsDefault)
+
+	groups, err := client.GetSuppressionGroups(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, groups, 2)
+
+	assert.NoError(t, client.DeleteSuppressionGroup(ctx, a.ID))
+	assert.Len(t, fake.SuppressionGroups(), 1)
+}
+
+func TestGroupSuppressions(t *testing.T) {
+	fake, client := setup(t)
+	ctx := context.TODO()
+
+	g, err := client.CreateSuppressionGroup(ctx, &sendgrid.InputCreateSuppressionGroup{Name: "news", Description: "Newsletter"})
+	assert.NoError(t, err)
+
+	_, err = client.AddSuppressionsToGroup(ctx, g.ID, &sendgrid.InputAddSuppressionsToGroup{RecipientEmails: []string{"A@example.com", "b@example.com"}})
+	assert.NoError(t, err)
+	emails, err := client.GetSuppressionGroupSuppressions(ctx, g.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a@example.com", "b@example.com"}, emails)
+
+	assert.NoError(t, client.DeleteSuppressionFromGroup(ctx, g.ID, "a@example.com"))
+	assert.Equal(t, []string{"b@example.com"}, fake.GroupSuppressions(g.ID))
+	err = client.DeleteSuppressionFromGroup(ctx, g.ID, "a@example.com")
+	assert.True(t, sendgrid.IsNotFound(err))
+
+	_, err = client.AddSuppressionsToGroup(ctx, g.ID+1, &sendgrid.InputAddSuppressionsToGroup{RecipientEmails: []string{"a@example.com"}})
+	assert.True(t, sendgrid.IsNotFound(err))
+}
+
+func TestUnsubscribeHandler(t *testing.T) {
+	fake, client := setup(t)
+	ctx := context.TODO()
+
+	g, err := client.CreateSuppressionGroup(ctx, &sendgrid.InputCreateSuppressionGroup{Name: "news", Description: "Newsletter"})
+	assert.NoError(t, err)
+
+	l := &sendgrid.ListUnsubscribe{URL: "https://example.com/unsubscribe", Secret: []byte("secret")}
+	link, err := l.URLFor("a@example.com", g.ID)
+	assert.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, link, strings.NewReader(sendgrid.ListUnsubscribeOneClick))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	sendgrid.NewUnsubscribeHandler(client, l).ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{"a@example.com"}, fake.GroupSuppressions(g.ID))
+}
@@ -0,0 +1,335 @@
+package sendgridtest
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/i10416/sendgrid"
+)
+
+type template struct {
+	ID         string             `json:"id"`
+	Name       string             `json:"name"`
+	Generation string             `json:"generation"`
+	UpdatedAt  string             `json:"updated_at"`
+	Versions   []*templateVersion `json:"versions"`
+}
+
+type templateVersion struct {
+	ID                   string `json:"id"`
+	TemplateID           string `json:"template_id"`
+	Active               int    `json:"active"`
+	Name                 string `json:"name"`
+	HTMLContent          string `json:"html_content,omitempty"`
+	PlainContent         string `json:"plain_content,omitempty"`
+	GeneratePlainContent bool   `json:"generate_plain_content"`
+	Subject              string `json:"subject,omitempty"`
+	Editor               string `json:"editor,omitempty"`
+	TestData             string `json:"test_data,omitempty"`
+	UpdatedAt            string `json:"updated_at"`
+}
+
+// Templates returns the stored templates with their versions.
+func (s *Server) Templates() []*sendgrid.OutputGetTemplate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	templates := make([]*sendgrid.OutputGetTemplate, 0, len(s.templates))
+	for _, t := range s.templates {
+		out := &sendgrid.OutputGetTemplate{
+			ID:         t.ID,
+			Name:       t.Name,
+			Generation: t.Generation,
+			UpdatedAt:  t.UpdatedAt,
+		}
+		for _, v := range t.Versions {
+			out.Versions = append(out.Versions, sendgrid.Version{
+				ID:                   v.ID,
+				TemplateID:           v.TemplateID,
+				Name:                 v.Name,
+				Subject:              v.Subject,
+				UpdatedAt:            v.UpdatedAt,
+				GeneratePlainContent: v.GeneratePlainContent,
+				HTMLContent:          v.HTMLContent,
+				PlainContent:         v.PlainContent,
+				Editor:               v.Editor,
+			})
+		}
+		templates = append(templates, out)
+	}
+	return templates
+}
+
+func (s *Server) templateRoutes() {
+	s.handle("GET /templates", s.getTemplates)
+	s.handle("POST /templates", s.createTemplate)
+	s.handle("GET /templates/{id}", s.getTemplate)
+	s.handle("POST /templates/{id}", s.duplicateTemplate)
+	s.handle("PATCH /templates/{id}", s.updateTemplate)
+	s.handle("DELETE /templates/{id}", s.deleteTemplate)
+	s.handle("POST /templates/{id}/versions", s.createTemplateVersion)
+	s.handle("GET /templates/{id}/versions/{version}", s.getTemplateVersion)
+	s.handle("PATCH /templates/{id}/versions/{version}", s.updateTemplateVersion)
+	s.handle("DELETE /templates/{id}/versions/{version}", s.deleteTemplateVersion)
+	s.handle("POST /templates/{id}/versions/{version}/activate", s.activateTemplateVersion)
+}
+
+func (s *Server) findTemplate(w http.ResponseWriter, r *http.Request) (int, *template) {
+	id := r.PathValue("id")
+	i := slices.IndexFunc(s.templates, func(t *template) bool { return t.ID == id })
+	if i < 0 {
+		writeNotFound(w, "template")
+		return -1, nil
+	}
+	return i, s.templates[i]
+}
+
+func (s *Server) findTemplateVersion(w http.ResponseWriter, r *http.Request) (*template, int, *templateVersion) {
+	_, t := s.findTemplate(w, r)
+	if t == nil {
+		return nil, -1, nil
+	}
+	id := r.PathValue("version")
+	i := slices.IndexFunc(t.Versions, func(v *templateVersion) bool { return v.ID == id })
+	if i < 0 {
+		writeNotFound(w, "template version")
+		return nil, -1, nil
+	}
+	return t, i, t.Versions[i]
+}
+
+func (s *Server) newTemplateID(generation string) string {
+	if generation == "dynamic" {
+		return fmt.Sprintf("d-%032x", s.id())
+	}
+	return fmt.Sprintf("00000000-0000-4000-8000-%012x", s.id())
+}
+
+func (s *Server) getTemplates(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	generations := strings.Split(q.Get("generations"), ",")
+	if q.Get("generations") == "" {
+		generations = []string{"legacy"}
+	}
+	var matched []*template
+	for _, t := range s.templates {
+		if slices.Contains(generations, t.Generation) {
+			matched = append(matched, t)
+		}
+	}
+
+	size, _ := strconv.Atoi(q.Get("page_size"))
+	if size <= 0 {
+		size = len(matched)
+	}
+	start, _ := strconv.Atoi(q.Get("page_token"))
+	start = min(max(start, 0), len(matched))
+	end := min(start+size, len(matched))
+
+	out := struct {
+		Result   []*template       `json:"result"`
+		Metadata sendgrid.Metadata `json:"_metadata"`
+	}{
+		Result:   matched[start:end],
+		Metadata: sendgrid.Metadata{Count: len(matched)},
+	}
+	if out.Result == nil {
+		out.Result = []*template{}
+	}
+	if end < len(matched) {
+		next := url.Values{}
+		for k, v := range q {
+			next[k] = v
+		}
+		next.Set("page_token", strconv.Itoa(end))
+		out.Metadata.Next = s.URL + "/templates?" + next.Encode()
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) createTemplate(w http.ResponseWriter, r *http.Request) {
+	var in sendgrid.InputCreateTemplate
+	if !decode(w, r, &in) {
+		return
+	}
+	if in.Name == "" {
+		writeError(w, http.StatusBadRequest, "name", "name is required")
+		return
+	}
+	if in.Generation == "" {
+		in.Generation = "legacy"
+	}
+
+	t := &template{
+		ID:         s.newTemplateID(in.Generation),
+		Name:       in.Name,
+		Generation: in.Generation,
+		UpdatedAt:  s.timestamp(),
+		Versions:   []*templateVersion{},
+	}
+	s.templates = append(s.templates, t)
+	writeJSON(w, http.StatusCreated, t)
+}
+
+func (s *Server) getTemplate(w http.ResponseWriter, r *http.Request) {
+	if _, t := s.findTemplate(w, r); t != nil {
+		writeJSON(w, http.StatusOK, t)
+	}
+}
+
+func (s *Server) duplicateTemplate(w http.ResponseWriter, r *http.Request) {
+	_, t := s.findTemplate(w, r)
+	if t == nil {
+		return
+	}
+	var in sendgrid.InputDuplicateTemplate
+	if !decode(w, r, &in) {
+		return
+	}
+	if in.Name == "" {
+		in.Name = "Duplicate: " + t.Name
+	}
+
+	dup := &template{
+		ID:         s.newTemplateID(t.Generation),
+		Name:       in.Name,
+		Generation: t.Generation,
+		UpdatedAt:  s.timestamp(),
+		Versions:   make([]*templateVersion, 0, len(t.Versions)),
+	}
+	for _, v := range t.Versions {
+		cp := *v
+		cp.ID = s.newTemplateID("legacy")
+		cp.TemplateID = dup.ID
+		dup.Versions = append(dup.Versions, &cp)
+	}
+	s.templates = append(s.templates, dup)
+	writeJSON(w, http.StatusCreated, dup)
+}
+
+func (s *Server) updateTemplate(w http.ResponseWriter, r *http.Request) {
+	_, t := s.findTemplate(w, r)
+	if t == nil {
+		return
+	}
+	var in sendgrid.InputUpdateTemplate
+	if !decode(w, r, &in) {
+		return
+	}
+	if in.Name != "" {
+		t.Name = in.Name
+	}
+	t.UpdatedAt = s.timestamp()
+	writeJSON(w, http.StatusOK, t)
+}
+
+func (s *Server) deleteTemplate(w http.ResponseWriter, r *http.Request) {
+	if i, t := s.findTemplate(w, r); t != nil {
+		s.templates = slices.Delete(s.templates, i, i+1)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) createTemplateVersion(w http.ResponseWriter, r *http.Request) {
+	_, t := s.findTemplate(w, r)
+	if t == nil {
+		return
+	}
+	var in sendgrid.InputCreateTemplateVersion
+	if !decode(w, r, &in) {
+		return
+	}
+	if in.Name == "" {
+		writeError(w, http.StatusBadRequest, "name", "name is required")
+		return
+	}
+
+	v := &templateVersion{
+		ID:                   s.newTemplateID("legacy"),
+		TemplateID:           t.ID,
+		Active:               in.Active,
+		Name:                 in.Name,
+		HTMLContent:          in.HTMLContent,
+		PlainContent:         in.PlainContent,
+		GeneratePlainContent: in.GeneratePlainContent,
+		Subject:              in.Subject,
+		Editor:               in.Editor,
+		TestData:             in.TestData,
+		UpdatedAt:            s.timestamp(),
+	}
+	t.Versions = append(t.Versions, v)
+	if v.Active == 1 {
+		t.activate(v)
+	}
+	writeJSON(w, http.StatusCreated, v)
+}
+
+func (s *Server) getTemplateVersion(w http.ResponseWriter, r *http.Request) {
+	if _, _, v := s.findTemplateVersion(w, r); v != nil {
+		writeJSON(w, http.StatusOK, v)
+	}
+}
+
+func (s *Server) updateTemplateVersion(w http.ResponseWriter, r *http.Request) {
+	t, _, v := s.findTemplateVersion(w, r)
+	if v == nil {
+		return
+	}
+	var in sendgrid.InputUpdateTemplateVersion
+	if !decode(w, r, &in) {
+		return
+	}
+
+	if in.Name != "" {
+		v.Name = in.Name
+	}
+	if in.HTMLContent != "" {
+		v.HTMLContent = in.HTMLContent
+	}
+	if in.PlainContent != "" {
+		v.PlainContent = in.PlainContent
+	}
+	if in.Subject != "" {
+		v.Subject = in.Subject
+	}
+	if in.Editor != "" {
+		v.Editor = in.Editor
+	}
+	if in.TestData != "" {
+		v.TestData = in.TestData
+	}
+	v.GeneratePlainContent = in.GeneratePlainContent
+	v.UpdatedAt = s.timestamp()
+	if in.Active == 1 {
+		t.activate(v)
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+func (s *Server) deleteTemplateVersion(w http.ResponseWriter, r *http.Request) {
+	if t, i, v := s.findTemplateVersion(w, r); v != nil {
+		t.Versions = slices.Delete(t.Versions, i, i+1)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) activateTemplateVersion(w http.ResponseWriter, r *http.Request) {
+	if t, _, v := s.findTemplateVersion(w, r); v != nil {
+		t.activate(v)
+		v.UpdatedAt = s.timestamp()
+		writeJSON(w, http.StatusOK, v)
+	}
+}
+
+// activate makes v the only active version of t.
+func (t *template) activate(v *templateVersion) {
+	for _, other := range t.Versions {
+		other.Active = 0
+	}
+	v.Active = 1
+}
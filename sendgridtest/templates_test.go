@@ -0,0 +1,88 @@
+package sendgridtest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/i10416/sendgrid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplates(t *testing.T) {
+	fake, client := setup(t)
+	ctx := context.TODO()
+
+	created, err := client.CreateTemplate(ctx, &sendgrid.InputCreateTemplate{Name: "welcome", Generation: "dynamic"})
+	assert.NoError(t, err)
+	assert.Regexp(t, `^d-[0-9a-f]{32}$`, created.ID)
+
+	_, err = client.UpdateTemplate(ctx, created.ID, &sendgrid.InputUpdateTemplate{Name: "welcome-v2"})
+	assert.NoError(t, err)
+
+	got, err := client.GetTemplate(ctx, created.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "welcome-v2", got.Name)
+	assert.Equal(t, "dynamic", got.Generation)
+
+	dup, err := client.DuplicateTemplate(ctx, created.ID, &sendgrid.InputDuplicateTemplate{Name: "copy"})
+	assert.NoError(t, err)
+	assert.NotEqual(t, created.ID, dup.ID)
+	assert.Len(t, fake.Templates(), 2)
+
+	assert.NoError(t, client.DeleteTemplate(ctx, dup.ID))
+	_, err = client.GetTemplate(ctx, dup.ID)
+	assert.True(t, sendgrid.IsNotFound(err))
+}
+
+func TestTemplatesPagination(t *testing.T) {
+	_, client := setup(t)
+	ctx := context.TODO()
+
+	for _, name := range []string{"a", "b", "c"} {
+		_, err := client.CreateTemplate(ctx, &sendgrid.InputCreateTemplate{Name: name, Generation: "dynamic"})
+		assert.NoError(t, err)
+	}
+	_, err := client.CreateTemplate(ctx, &sendgrid.InputCreateTemplate{Name: "legacy"})
+	assert.NoError(t, err)
+
+	var names []string
+	for tmpl, err := range client.AllTemplates(ctx, &sendgrid.InputGetTemplates{Generations: "dynamic", PageSize: 2}) {
+		assert.NoError(t, err)
+		names = append(names, tmpl.Name)
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, names)
+}
+
+func TestTemplateVersions(t *testing.T) {
+	fake, client := setup(t)
+	ctx := context.TODO()
+
+	tmpl, err := client.CreateTemplate(ctx, &sendgrid.InputCreateTemplate{Name: "welcome", Generation: "dynamic"})
+	assert.NoError(t, err)
+
+	v1, err := client.CreateTemplateVersion(ctx, tmpl.ID, &sendgrid.InputCreateTemplateVersion{Name: "v1", Active: 1, Subject: "Hi {{name}}", HTMLContent: "<p>Hi</p>"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v1.Active)
+	v2, err := client.CreateTemplateVersion(ctx, tmpl.ID, &sendgrid.InputCreateTemplateVersion{Name: "v2", Subject: "Hello"})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, v2.Active)
+
+	activated, err := client.ActivateTemplateVersion(ctx, tmpl.ID, v2.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, activated.Active)
+
+	got, err := client.GetTemplateVersion(ctx, tmpl.ID, v1.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, got.Active)
+
+	updated, err := client.UpdateTemplateVersion(ctx, tmpl.ID, v1.ID, &sendgrid.InputUpdateTemplateVersion{Subject: "Updated"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Updated", updated.Subject)
+	assert.Equal(t, "v1", updated.Name)
+
+	assert.NoError(t, client.DeleteTemplateVersion(ctx, tmpl.ID, v1.ID))
+	templates := fake.Templates()
+	if assert.Len(t, templates, 1) && assert.Len(t, templates[0].Versions, 1) {
+		assert.Equal(t, v2.ID, templates[0].Versions[0].ID)
+	}
+}
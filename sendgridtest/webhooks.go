@@ -0,0 +1,293 @@
+package sendgridtest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/i10416/sendgrid"
+)
+
+// maxEventWebhooks is the number of event webhooks SendGrid allows per
+// account.
+const maxEventWebhooks = 5
+
+type eventWebhook struct {
+	sendgrid.EventWebhook
+	CreatedDate string `json:"created_date,omitempty"`
+	UpdatedDate string `json:"updated_date,omitempty"`
+
+	key *ecdsa.PrivateKey
+}
+
+// EventWebhooks returns the stored event webhooks.
+func (s *Server) EventWebhooks() []sendgrid.EventWebhook {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	webhooks := make([]sendgrid.EventWebhook, 0, len(s.eventWebhooks))
+	for _, h := range s.eventWebhooks {
+		webhooks = append(webhooks, h.EventWebhook)
+	}
+	return webhooks
+}
+
+// EventWebhookSigningKey returns the private key of an event webhook with
+// signature verification enabled, so tests can sign events the way SendGrid
+// does. It returns nil if the webhook does not exist or is not signed.
+func (s *Server) EventWebhookSigningKey(id string) *ecdsa.PrivateKey {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, h := range s.eventWebhooks {
+		if h.ID == id {
+			return h.key
+		}
+	}
+	return nil
+}
+
+// InboundParseWebhooks returns the stored inbound parse settings.
+func (s *Server) InboundParseWebhooks() []sendgrid.InboundParseWebhook {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	webhooks := make([]sendgrid.InboundParseWebhook, 0, len(s.parseWebhooks))
+	for _, h := range s.parseWebhooks {
+		webhooks = append(webhooks, *h)
+	}
+	return webhooks
+}
+
+func (s *Server) webhookRoutes() {
+	s.handle("GET /user/webhooks/event/settings/all", s.getEventWebhooks)
+	s.handle("POST /user/webhooks/event/settings", s.createEventWebhook)
+	s.handle("GET /user/webhooks/event/settings/{id}", s.getEventWebhook)
+	s.handle("PATCH /user/webhooks/event/settings/{id}", s.updateEventWebhook)
+	s.handle("DELETE /user/webhooks/event/settings/{id}", s.deleteEventWebhook)
+	s.handle("GET /user/webhooks/event/settings/signed/{id}", s.getSignedEventWebhook)
+	s.handle("PATCH /user/webhooks/event/settings/signed/{id}", s.toggleSignatureVerification)
+
+	s.handle("GET /user/webhooks/parse/settings", s.getInboundParseWebhooks)
+	s.handle("POST /user/webhooks/parse/settings", s.createInboundParseWebhook)
+	s.handle("GET /user/webhooks/parse/settings/{hostname}", s.getInboundParseWebhook)
+	s.handle("PATCH /user/webhooks/parse/settings/{hostname}", s.updateInboundParseWebhook)
+	s.handle("DELETE /user/webhooks/parse/settings/{hostname}", s.deleteInboundParseWebhook)
+}
+
+func (s *Server) findEventWebhook(w http.ResponseWriter, r *http.Request) (int, *eventWebhook) {
+	id := r.PathValue("id")
+	i := slices.IndexFunc(s.eventWebhooks, func(h *eventWebhook) bool { return h.ID == id })
+	if i < 0 {
+		writeNotFound(w, "event webhook")
+		return -1, nil
+	}
+	return i, s.eventWebhooks[i]
+}
+
+func (s *Server) getEventWebhooks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, struct {
+		MaxAllowed int             `json:"max_allowed"`
+		Webhooks   []*eventWebhook `json:"webhooks"`
+	}{maxEventWebhooks, s.eventWebhooks})
+}
+
+func (s *Server) createEventWebhook(w http.ResponseWriter, r *http.Request) {
+	var in sendgrid.InputCreateEventWebhook
+	if !decode(w, r, &in) {
+		return
+	}
+	if in.URL == "" {
+		writeError(w, http.StatusBadRequest, "url", "url is required")
+		return
+	}
+	if len(s.eventWebhooks) >= maxEventWebhooks {
+		writeError(w, http.StatusBadRequest, "", fmt.Sprintf("the maximum of %d event webhooks has been reached", maxEventWebhooks))
+		return
+	}
+
+	h := &eventWebhook{
+		EventWebhook: sendgrid.EventWebhook{
+			ID:               fmt.Sprintf("%08x-0000-4000-8000-000000000000", s.id()),
+			Enabled:          in.Enabled,
+			URL:              in.URL,
+			GroupResubscribe: in.GroupResubscribe,
+			Delivered:        in.Delivered,
+			GroupUnsubscribe: in.GroupUnsubscribe,
+			SpamReport:       in.SpamReport,
+			Bounce:           in.Bounce,
+			Deferred:         in.Deferred,
+			Unsubscribe:      in.Unsubscribe,
+			Processed:        in.Processed,
+			Open:             in.Open,
+			Click:            in.Click,
+			Dropped:          in.Dropped,
+			FriendlyName:     in.FriendlyName,
+			OAuthClientID:    in.OAuthClientID,
+			OAuthTokenURL:    in.OAuthTokenURL,
+		},
+		CreatedDate: s.timestamp(),
+	}
+	h.UpdatedDate = h.CreatedDate
+	s.eventWebhooks = append(s.eventWebhooks, h)
+	writeJSON(w, http.StatusCreated, h)
+}
+
+func (s *Server) getEventWebhook(w http.ResponseWriter, r *http.Request) {
+	if _, h := s.findEventWebhook(w, r); h != nil {
+		writeJSON(w, http.StatusOK, h)
+	}
+}
+
+func (s *Server) updateEventWebhook(w http.ResponseWriter, r *http.Request) {
+	_, h := s.findEventWebhook(w, r)
+	if h == nil {
+		return
+	}
+	var in sendgrid.InputUpdateEventWebhook
+	if !decode(w, r, &in) {
+		return
+	}
+
+	h.Enabled = in.Enabled
+	if in.URL != "" {
+		h.URL = in.URL
+	}
+	h.GroupResubscribe = in.GroupResubscribe
+	h.Delivered = in.Delivered
+	h.GroupUnsubscribe = in.GroupUnsubscribe
+	h.SpamReport = in.SpamReport
+	h.Bounce = in.Bounce
+	h.Deferred = in.Deferred
+	h.Unsubscribe = in.Unsubscribe
+	h.Processed = in.Processed
+	h.Open = in.Open
+	h.Click = in.Click
+	h.Dropped = in.Dropped
+	if in.FriendlyName != "" {
+		h.FriendlyName = in.FriendlyName
+	}
+	if in.OAuthClientID != "" {
+		h.OAuthClientID = in.OAuthClientID
+	}
+	if in.OAuthTokenURL != "" {
+		h.OAuthTokenURL = in.OAuthTokenURL
+	}
+	h.UpdatedDate = s.timestamp()
+	writeJSON(w, http.StatusOK, h)
+}
+
+func (s *Server) deleteEventWebhook(w http.ResponseWriter, r *http.Request) {
+	if i, h := s.findEventWebhook(w, r); h != nil {
+		s.eventWebhooks = slices.Delete(s.eventWebhooks, i, i+1)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) getSignedEventWebhook(w http.ResponseWriter, r *http.Request) {
+	if _, h := s.findEventWebhook(w, r); h != nil {
+		writeJSON(w, http.StatusOK, sendgrid.OutputToggleSignatureVerification{ID: h.ID, PublicKey: h.PublicKey})
+	}
+}
+
+func (s *Server) toggleSignatureVerification(w http.ResponseWriter, r *http.Request) {
+	_, h := s.findEventWebhook(w, r)
+	if h == nil {
+		return
+	}
+	var in sendgrid.InputToggleSignatureVerification
+	if !decode(w, r, &in) {
+		return
+	}
+
+	h.key, h.PublicKey = nil, ""
+	if in.Enabled {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "", err.Error())
+			return
+		}
+		der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "", err.Error())
+			return
+		}
+		h.key, h.PublicKey = key, base64.StdEncoding.EncodeToString(der)
+	}
+	writeJSON(w, http.StatusOK, sendgrid.OutputToggleSignatureVerification{ID: h.ID, PublicKey: h.PublicKey})
+}
+
+func (s *Server) findInboundParseWebhook(w http.ResponseWriter, r *http.Request) (int, *sendgrid.InboundParseWebhook) {
+	hostname := r.PathValue("hostname")
+	i := slices.IndexFunc(s.parseWebhooks, func(h *sendgrid.InboundParseWebhook) bool { return h.Hostname == hostname })
+	if i < 0 {
+		writeNotFound(w, "inbound parse webhook")
+		return -1, nil
+	}
+	return i, s.parseWebhooks[i]
+}
+
+func (s *Server) getInboundParseWebhooks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, struct {
+		Result []*sendgrid.InboundParseWebhook `json:"result"`
+	}{s.parseWebhooks})
+}
+
+func (s *Server) createInboundParseWebhook(w http.ResponseWriter, r *http.Request) {
+	var in sendgrid.InputCreateInboundParseWebhook
+	if !decode(w, r, &in) {
+		return
+	}
+	switch {
+	case in.Hostname == "":
+		writeError(w, http.StatusBadRequest, "hostname", "hostname is required")
+		return
+	case in.URL == "":
+		writeError(w, http.StatusBadRequest, "url", "url is required")
+		return
+	case slices.ContainsFunc(s.parseWebhooks, func(h *sendgrid.InboundParseWebhook) bool { return h.Hostname == in.Hostname }):
+		writeError(w, http.StatusBadRequest, "hostname", "a setting for this hostname already exists")
+		return
+	}
+
+	h := &sendgrid.InboundParseWebhook{
+		URL:       in.URL,
+		Hostname:  in.Hostname,
+		SpamCheck: in.SpamCheck,
+		SendRaw:   in.SendRaw,
+	}
+	s.parseWebhooks = append(s.parseWebhooks, h)
+	writeJSON(w, http.StatusCreated, h)
+}
+
+func (s *Server) getInboundParseWebhook(w http.ResponseWriter, r *http.Request) {
+	if _, h := s.findInboundParseWebhook(w, r); h != nil {
+		writeJSON(w, http.StatusOK, h)
+	}
+}
+
+func (s *Server) updateInboundParseWebhook(w http.ResponseWriter, r *http.Request) {
+	_, h := s.findInboundParseWebhook(w, r)
+	if h == nil {
+		return
+	}
+	var in sendgrid.InputUpdateInboundParseWebhook
+	if !decode(w, r, &in) {
+		return
+	}
+	if in.URL != "" {
+		h.URL = in.URL
+	}
+	h.SpamCheck = in.SpamCheck
+	h.SendRaw = in.SendRaw
+	writeJSON(w, http.StatusOK, h)
+}
+
+func (s *Server) deleteInboundParseWebhook(w http.ResponseWriter, r *http.Request) {
+	if i, h := s.findInboundParseWebhook(w, r); h != nil {
+		s.parseWebhooks = slices.Delete(s.parseWebhooks, i, i+1)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,111 @@
+package sendgridtest
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/i10416/sendgrid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventWebhooks(t *testing.T) {
+	fake, client := setup(t)
+	ctx := context.TODO()
+
+	created, err := client.CreateEventWebhook(ctx, &sendgrid.InputCreateEventWebhook{Enabled: true, URL: "https://example.com/events", Delivered: true})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, created.ID)
+
+	updated, err := client.UpdateEventWebhook(ctx, created.ID, &sendgrid.InputUpdateEventWebhook{Enabled: true, Bounce: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/events", updated.URL)
+	assert.True(t, updated.Bounce)
+	assert.False(t, updated.Delivered)
+
+	signed, err := client.ToggleSignatureVerification(ctx, created.ID, &sendgrid.InputToggleSignatureVerification{Enabled: true})
+	assert.NoError(t, err)
+	der, err := base64.StdEncoding.DecodeString(signed.PublicKey)
+	assert.NoError(t, err)
+	pub, err := x509.ParsePKIXPublicKey(der)
+	assert.NoError(t, err)
+	if key := fake.EventWebhookSigningKey(created.ID); assert.NotNil(t, key) {
+		assert.True(t, key.PublicKey.Equal(pub))
+	}
+
+	publicKey, err := client.GetSignedEventWebhooksPublicKey(ctx, created.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, signed.PublicKey, publicKey.PublicKey)
+
+	all, err := client.GetEventWebhooks(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, all.Webhooks, 1)
+
+	assert.NoError(t, client.DeleteEventWebhook(ctx, created.ID))
+	assert.Empty(t, fake.EventWebhooks())
+}
+
+func TestEventWebhookHandler(t *testing.T) {
+	fake, client := setup(t)
+	ctx := context.TODO()
+
+	created, err := client.CreateEventWebhook(ctx, &sendgrid.InputCreateEventWebhook{Enabled: true, URL: "https://example.com/events", Delivered: true})
+	assert.NoError(t, err)
+	signed, err := client.ToggleSignatureVerification(ctx, created.ID, &sendgrid.InputToggleSignatureVerification{Enabled: true})
+	assert.NoError(t, err)
+
+	h, err := sendgrid.NewEventWebhookHandler(signed.PublicKey)
+	if !assert.NoError(t, err) {
+		return
+	}
+	var delivered []string
+	sendgrid.OnEvent(h, func(ctx context.Context, e *sendgrid.DeliveredEvent) error {
+		delivered = append(delivered, e.SGMessageID)
+		return nil
+	})
+
+	// sign the batch the way SendGrid does
+	body := `[{"email":"a@example.com","timestamp":1700000000,"event":"delivered","sg_event_id":"ev-1","sg_message_id":"msg-1.filter"}]`
+	timestamp := "1700000000"
+	digest := sha256.Sum256([]byte(timestamp + body))
+	sig, err := ecdsa.SignASN1(rand.Reader, fake.EventWebhookSigningKey(created.ID), digest[:])
+	assert.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(body))
+	req.Header.Set(sendgrid.HeaderEventWebhookSignature, base64.StdEncoding.EncodeToString(sig))
+	req.Header.Set(sendgrid.HeaderEventWebhookTimestamp, timestamp)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{"msg-1.filter"}, delivered)
+}
+
+func TestInboundParseWebhooks(t *testing.T) {
+	fake, client := setup(t)
+	ctx := context.TODO()
+
+	_, err := client.CreateInboundParseWebhook(ctx, &sendgrid.InputCreateInboundParseWebhook{Hostname: "parse.example.com", URL: "https://example.com/parse"})
+	assert.NoError(t, err)
+
+	_, err = client.UpdateInboundParseWebhook(ctx, "parse.example.com", &sendgrid.InputUpdateInboundParseWebhook{SendRaw: true})
+	assert.NoError(t, err)
+
+	got, err := client.GetInboundParseWebhook(ctx, "parse.example.com")
+	assert.NoError(t, err)
+	assert.True(t, got.SendRaw)
+	assert.Equal(t, "https://example.com/parse", got.URL)
+
+	all, err := client.GetInboundParseWebhooks(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, all, 1)
+
+	assert.NoError(t, client.DeleteInboundParseWebhook(ctx, "parse.example.com"))
+	assert.Empty(t, fake.InboundParseWebhooks())
+}
@@ -0,0 +1,71 @@
+package sendgrid
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OptionSlog sends the logs of the client to l. Every request attempt is
+// logged as a structured record with the operation, method, path, status,
+// duration, remaining rate limit and subuser. Debug lines are logged at
+// slog.LevelDebug instead of through the logger set with OptionLog.
+//
+// Failures to write logs are left to the handler of l; they never terminate
+// the process.
+func OptionSlog(l *slog.Logger) func(*Client) {
+	return func(c *Client) {
+		c.slog = l
+	}
+}
+
+// logAttempt logs a single attempt of req if a slog logger is configured.
+// Failed attempts are logged at slog.LevelWarn.
+func (c *Client) logAttempt(ctx context.Context, req *http.Request, resp *http.Response, err error, attempt int, d time.Duration) {
+	if c.slog == nil {
+		return
+	}
+
+	level := slog.LevelInfo
+	if err != nil {
+		level = slog.LevelWarn
+	}
+	if !c.slog.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := make([]slog.Attr, 0, 9)
+	if op := OperationName(ctx); op != "" {
+		attrs = append(attrs, slog.String("operation", op))
+	}
+	attrs = append(attrs,
+		slog.String("method", req.Method),
+		slog.String("path", req.URL.Path),
+	)
+	if resp != nil {
+		attrs = append(attrs, slog.Int("status", resp.StatusCode))
+	}
+	attrs = append(attrs,
+		slog.Duration("duration", d),
+		slog.Int("attempt", attempt),
+	)
+	if resp != nil {
+		if rl, ok := parseRateLimit(resp.Header); ok {
+			attrs = append(attrs, slog.Int("rate_limit_remaining", rl.Remaining))
+		}
+	}
+	if subuser := req.Header.Get("On-Behalf-Of"); subuser != "" {
+		attrs = append(attrs, slog.String("subuser", subuser))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	c.slog.LogAttrs(ctx, level, "sendgrid request", attrs...)
+}
+
+// slogDebug logs a debug line through the slog logger.
+func (c *Client) slogDebug(msg string) {
+	c.slog.Debug(strings.TrimSuffix(msg, "\n"))
+}
@@ -0,0 +1,113 @@
+package sendgrid
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeRecords(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var records []map[string]interface{}
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var r map[string]interface{}
+		assert.NoError(t, dec.Decode(&r))
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestOptionSlog(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	var buf bytes.Buffer
+	OptionSlog(slog.New(slog.NewJSONHandler(&buf, nil)))(client)
+
+	reset := time.Now().Add(time.Minute).Unix()
+	mux.HandleFunc("/templates/d-12345abcde", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Limit", "500")
+		w.Header().Set("X-RateLimit-Remaining", "499")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+		fmt.Fprint(w, `{"id": "d-12345abcde"}`)
+	})
+
+	_, err := client.GetTemplate(context.TODO(), "d-12345abcde")
+	assert.NoError(t, err)
+
+	records := decodeRecords(t, &buf)
+	if assert.Len(t, records, 1) {
+		r := records[0]
+		assert.Equal(t, "INFO", r["level"])
+		assert.Equal(t, "sendgrid request", r["msg"])
+		assert.Equal(t, "GetTemplate", r["operation"])
+		assert.Equal(t, "GET", r["method"])
+		assert.Equal(t, "/v3/templates/d-12345abcde", r["path"])
+		assert.Equal(t, float64(200), r["status"])
+		assert.Equal(t, float64(1), r["attempt"])
+		assert.Equal(t, float64(499), r["rate_limit_remaining"])
+		assert.Equal(t, "dummy", r["subuser"])
+		assert.Contains(t, r, "duration")
+	}
+}
+
+func TestOptionSlogError(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	var buf bytes.Buffer
+	OptionSlog(slog.New(slog.NewJSONHandler(&buf, nil)))(client)
+	OptionRetry(testRetryPolicy())(client)
+
+	mux.HandleFunc("/templates/d-12345abcde", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	_, err := client.GetTemplate(context.TODO(), "d-12345abcde")
+	assert.Error(t, err)
+
+	records := decodeRecords(t, &buf)
+	if assert.Len(t, records, 3) {
+		for i, r := range records {
+			assert.Equal(t, "WARN", r["level"])
+			assert.Equal(t, float64(503), r["status"])
+			assert.Equal(t, float64(i+1), r["attempt"])
+			assert.Contains(t, r["error"], "503 Service Unavailable")
+		}
+	}
+}
+
+func TestOptionSlogDebug(t *testing.T) {
+	originalLogFatal := logFatal
+	defer func() {
+		logFatal = originalLogFatal
+	}()
+	fatalCalled = false
+	logFatal = mockLogFatal
+
+	var buf bytes.Buffer
+	client := New("test-key",
+		OptionDebug(true),
+		OptionLog(&errorLogger{shouldError: true}),
+		OptionSlog(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))),
+	)
+	client.Debugf("hello %s", "world")
+	client.Debugln("bye")
+
+	assert.False(t, fatalCalled)
+	records := decodeRecords(t, &buf)
+	if assert.Len(t, records, 2) {
+		assert.Equal(t, "DEBUG", records[0]["level"])
+		assert.Equal(t, "hello world", records[0]["msg"])
+		assert.Equal(t, "bye", records[1]["msg"])
+	}
+}
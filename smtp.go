@@ -0,0 +1,314 @@
+package sendgrid
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// DefaultSMTPAddr is the address of the SendGrid SMTP relay.
+const DefaultSMTPAddr = "smtp.sendgrid.net:587"
+
+// smtpUsername is the username of API key authentication on the SMTP relay.
+const smtpUsername = "apikey"
+
+// maxSMTPAPILen is the longest X-SMTPAPI value written on a single line,
+// keeping the header line within the 998 characters of RFC 5322.
+const maxSMTPAPILen = 998 - len("X-SMTPAPI: ")
+
+// Sender sends mail. It is implemented by Client, which uses the Web API,
+// and by SMTPSender, which uses the SMTP relay.
+type Sender interface {
+	SendMail(ctx context.Context, input *InputSendMail) (*OutputSendMail, error)
+}
+
+var (
+	_ Sender = (*Client)(nil)
+	_ Sender = (*SMTPSender)(nil)
+)
+
+// SMTPAPIHeader is the X-SMTPAPI header that carries the SendGrid settings
+// of a mail sent through the SMTP relay.
+// see: https://www.twilio.com/docs/sendgrid/for-developers/sending-email/getting-started-smtp
+type SMTPAPIHeader struct {
+	UniqueArgs         map[string]string `json:"unique_args,omitempty"`
+	Category           []string          `json:"category,omitempty"`
+	ASMGroupID         int               `json:"asm_group_id,omitempty"`
+	ASMGroupsToDisplay []int             `json:"asm_groups_to_display,omitempty"`
+	SendAt             int64             `json:"send_at,omitempty"`
+	IPPool             string            `json:"ip_pool,omitempty"`
+}
+
+// SMTPSender sends mail through the SendGrid SMTP relay, for environments
+// that only allow SMTP egress. It connects to Addr, upgrades the connection
+// with STARTTLS and authenticates with the API key.
+//
+// Each personalization is sent as a separate message, built as described in
+// WriteEML without the Bcc header. Categories, custom args, ASM, send_at and
+// the IP pool are translated into the X-SMTPAPI header. Mail settings,
+// tracking settings, batch IDs and dynamic templates are not supported by
+// the relay and are ignored; render templates with RenderTemplateVersion
+// first.
+type SMTPSender struct {
+	// Addr is the host:port of the relay. It defaults to DefaultSMTPAddr.
+	Addr string
+	// TLSConfig configures STARTTLS. If it has no ServerName, the host of
+	// Addr is used.
+	TLSConfig *tls.Config
+	// LocalName is the name sent with EHLO. It defaults to "localhost".
+	LocalName string
+
+	apiKey string
+}
+
+// NewSMTPSender returns an SMTPSender that authenticates with apiKey.
+func NewSMTPSender(apiKey string) *SMTPSender {
+	return &SMTPSender{apiKey: apiKey}
+}
+
+// smtpMessage is a message with its envelope.
+type smtpMessage struct {
+	from string
+	to   []string
+	data []byte
+}
+
+// SendMail sends input through the SMTP relay. The MessageID of the output
+// is the ID that the relay reports for the first personalization. If an
+// error occurs, the personalizations before the failing one have already
+// been sent.
+func (s *SMTPSender) SendMail(ctx context.Context, input *InputSendMail) (*OutputSendMail, error) {
+	msgs, err := smtpMessages(input)
+	if err != nil {
+		return nil, err
+	}
+
+	addr := s.Addr
+	if addr == "" {
+		addr = DefaultSMTPAddr
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	// interrupt blocked reads and writes when ctx is done
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	ids, err := s.send(conn, host, msgs)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &OutputSendMail{MessageID: ids[0]}, nil
+}
+
+func (s *SMTPSender) send(conn net.Conn, host string, msgs []*smtpMessage) ([]string, error) {
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	defer c.Close()
+
+	localName := s.LocalName
+	if localName == "" {
+		localName = "localhost"
+	}
+	if err := c.Hello(localName); err != nil {
+		return nil, err
+	}
+	if ok, _ := c.Extension("STARTTLS"); !ok {
+		return nil, fmt.Errorf("smtp server %s does not support STARTTLS", host)
+	}
+	config := &tls.Config{}
+	if s.TLSConfig != nil {
+		config = s.TLSConfig.Clone()
+	}
+	if config.ServerName == "" {
+		config.ServerName = host
+	}
+	if err := c.StartTLS(config); err != nil {
+		return nil, err
+	}
+	if err := c.Auth(smtp.PlainAuth("", smtpUsername, s.apiKey, host)); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(msgs))
+	for _, msg := range msgs {
+		if err := c.Mail(msg.from); err != nil {
+			return nil, err
+		}
+		for _, to := range msg.to {
+			if err := c.Rcpt(to); err != nil {
+				return nil, err
+			}
+		}
+		id, err := smtpData(c, msg.data)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, c.Quit()
+}
+
+// smtpData sends data with the DATA command and returns the message ID from
+// the "250 Ok: queued as <id>" reply of the relay.
+func smtpData(c *smtp.Client, data []byte) (string, error) {
+	id, err := c.Text.Cmd("DATA")
+	if err != nil {
+		return "", err
+	}
+	c.Text.StartResponse(id)
+	_, _, err = c.Text.ReadResponse(354)
+	c.Text.EndResponse(id)
+	if err != nil {
+		return "", err
+	}
+
+	w := c.Text.DotWriter()
+	if _, err := w.Write(data); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	_, msg, err := c.Text.ReadResponse(250)
+	if err != nil {
+		return "", err
+	}
+	_, queued, _ := strings.Cut(msg, "queued as ")
+	return strings.TrimSpace(queued), nil
+}
+
+// smtpMessages builds a message for each personalization of m.
+func smtpMessages(m *InputSendMail) ([]*smtpMessage, error) {
+	if m.From == nil || m.From.Email == "" {
+		return nil, errors.New("from is required")
+	}
+	if m.TemplateID != "" && len(m.Content) == 0 {
+		return nil, errors.New("template_id is not supported by the smtp relay")
+	}
+
+	var msgs []*smtpMessage
+	for i, p := range m.Personalizations {
+		if p == nil {
+			continue
+		}
+		var to []string
+		for _, list := range [][]*Email{p.To, p.Cc, p.Bcc} {
+			for _, e := range list {
+				if e != nil {
+					to = append(to, e.Email)
+				}
+			}
+		}
+		if len(to) == 0 {
+			return nil, fmt.Errorf("personalization %d has no recipients", i)
+		}
+
+		h, body, err := m.message(i)
+		if err != nil {
+			return nil, err
+		}
+		h.Del("Bcc")
+		api, err := smtpAPIHeader(m, p)
+		if err != nil {
+			return nil, err
+		}
+		if api != "" {
+			h.Set("X-SMTPAPI", api)
+		}
+
+		var buf bytes.Buffer
+		writeMIMEHeader(&buf, h)
+		buf.Write(body)
+		msgs = append(msgs, &smtpMessage{from: m.From.Email, to: to, data: buf.Bytes()})
+	}
+	if len(msgs) == 0 {
+		return nil, errors.New("mail has no personalizations")
+	}
+	return msgs, nil
+}
+
+// smtpAPIHeader returns the X-SMTPAPI header value for personalization p of
+// m, or an empty string if there is nothing to set.
+func smtpAPIHeader(m *InputSendMail, p *Personalization) (string, error) {
+	api := SMTPAPIHeader{
+		Category: m.Categories,
+		SendAt:   m.SendAt,
+		IPPool:   m.IPPoolName,
+	}
+	if p.SendAt != 0 {
+		api.SendAt = p.SendAt
+	}
+	if m.ASM != nil {
+		api.ASMGroupID = m.ASM.GroupID
+		api.ASMGroupsToDisplay = m.ASM.GroupsToDisplay
+	}
+	if len(m.CustomArgs)+len(p.CustomArgs) > 0 {
+		api.UniqueArgs = map[string]string{}
+		for k, v := range m.CustomArgs {
+			api.UniqueArgs[k] = v
+		}
+		for k, v := range p.CustomArgs {
+			api.UniqueArgs[k] = v
+		}
+	}
+
+	b, err := json.Marshal(api)
+	if err != nil {
+		return "", err
+	}
+	if string(b) == "{}" {
+		return "", nil
+	}
+	b = asciiJSON(b)
+	if len(b) <= maxSMTPAPILen {
+		return string(b), nil
+	}
+	// fold long values between JSON tokens; unfolding leaves a space there
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, b, " ", ""); err != nil {
+		return "", err
+	}
+	return strings.ReplaceAll(buf.String(), "\n", "\r\n"), nil
+}
+
+// asciiJSON escapes the non-ASCII characters of the JSON text b, since
+// header values are ASCII.
+func asciiJSON(b []byte) []byte {
+	var buf bytes.Buffer
+	for _, r := range string(b) {
+		switch {
+		case r < 0x80:
+			buf.WriteRune(r)
+		case r > 0xFFFF:
+			r1, r2 := utf16.EncodeRune(r)
+			fmt.Fprintf(&buf, `\u%04x\u%04x`, r1, r2)
+		default:
+			fmt.Fprintf(&buf, `\u%04x`, r)
+		}
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,80 @@
+package sendgrid
+
+import (
+	"bytes"
+	"context"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSMTPAPIHeader(t *testing.T) {
+	m := NewInputSendMail()
+	p := NewPersonalization()
+	api, err := smtpAPIHeader(m, p)
+	assert.NoError(t, err)
+	assert.Empty(t, api)
+
+	m.Categories = []string{"news"}
+	m.SendAt = 100
+	m.IPPoolName = "pool"
+	m.ASM = &ASM{GroupID: 1, GroupsToDisplay: []int{1, 2}}
+	m.CustomArgs = map[string]string{"a": "1", "b": "2"}
+	p.CustomArgs = map[string]string{"b": "3", "name": "José"}
+	p.SendAt = 200
+	api, err = smtpAPIHeader(m, p)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"unique_args":{"a":"1","b":"3","name":"Jos\u00e9"},"category":["news"],"asm_group_id":1,"asm_groups_to_display":[1,2],"send_at":200,"ip_pool":"pool"}`, api)
+}
+
+func TestSMTPAPIHeader_Fold(t *testing.T) {
+	m := NewInputSendMail()
+	for i := 0; i < 100; i++ {
+		m.Categories = append(m.Categories, strings.Repeat("c", 20))
+	}
+	api, err := smtpAPIHeader(m, NewPersonalization())
+	assert.NoError(t, err)
+	for _, line := range strings.Split(api, "\r\n") {
+		assert.LessOrEqual(t, len(line), maxSMTPAPILen)
+	}
+
+	// the header unfolds into the same JSON value
+	msg, err := mail.ReadMessage(strings.NewReader("X-SMTPAPI: " + api + "\r\n\r\n"))
+	assert.NoError(t, err)
+	assert.Contains(t, msg.Header.Get("X-SMTPAPI"), `"category": [ "cccc`)
+}
+
+func TestSMTPMessages(t *testing.T) {
+	m := NewInputSendMail()
+	m.SetFrom(NewEmail("from@example.com", ""))
+	m.AddContent(NewContent("text/plain", "Hi"))
+	p := NewPersonalization()
+	p.AddTo(NewEmail("to@example.com", ""))
+	p.AddCc(NewEmail("cc@example.com", ""))
+	p.AddBcc(NewEmail("bcc@example.com", ""))
+	m.AddPersonalization(p)
+
+	msgs, err := smtpMessages(m)
+	if !assert.NoError(t, err) || !assert.Len(t, msgs, 1) {
+		return
+	}
+	assert.Equal(t, "from@example.com", msgs[0].from)
+	assert.Equal(t, []string{"to@example.com", "cc@example.com", "bcc@example.com"}, msgs[0].to)
+	msg, err := mail.ReadMessage(bytes.NewReader(msgs[0].data))
+	assert.NoError(t, err)
+	assert.Empty(t, msg.Header.Get("Bcc"))
+	assert.Empty(t, msg.Header.Get("X-SMTPAPI"))
+	assert.Equal(t, "<cc@example.com>", msg.Header.Get("Cc"))
+
+	m.From = nil
+	_, err = smtpMessages(m)
+	assert.EqualError(t, err, "from is required")
+
+	m.SetFrom(NewEmail("from@example.com", ""))
+	m.Content = nil
+	m.SetTemplateID("d-123")
+	_, err = NewSMTPSender("key").SendMail(context.TODO(), m)
+	assert.EqualError(t, err, "template_id is not supported by the smtp relay")
+}
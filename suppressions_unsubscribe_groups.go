@@ -3,6 +3,7 @@ package sendgrid
 import (
 	"context"
 	"fmt"
+	"net/url"
 	"strconv"
 )
 
@@ -119,3 +120,59 @@ func (c *Client) DeleteSuppressionGroup(ctx context.Context, id int64) error {
 	}
 	return nil
 }
+
+// see: https://docs.sendgrid.com/api-reference/suppressions-suppressions/retrieve-all-suppressions-for-a-suppression-group
+func (c *Client) GetSuppressionGroupSuppressions(ctx context.Context, id int64) ([]string, error) {
+	path := fmt.Sprintf("/asm/groups/%s/suppressions", strconv.FormatInt(id, 10))
+
+	req, err := c.NewRequest("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	r := []string{}
+	if err := c.Do(ctx, req, &r); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+type InputAddSuppressionsToGroup struct {
+	RecipientEmails []string `json:"recipient_emails"`
+}
+
+type OutputAddSuppressionsToGroup struct {
+	RecipientEmails []string `json:"recipient_emails,omitempty"`
+}
+
+// see: https://docs.sendgrid.com/api-reference/suppressions-suppressions/add-suppressions-to-a-suppression-group
+func (c *Client) AddSuppressionsToGroup(ctx context.Context, id int64, input *InputAddSuppressionsToGroup) (*OutputAddSuppressionsToGroup, error) {
+	path := fmt.Sprintf("/asm/groups/%s/suppressions", strconv.FormatInt(id, 10))
+
+	req, err := c.NewRequest("POST", path, input)
+	if err != nil {
+		return nil, err
+	}
+
+	r := new(OutputAddSuppressionsToGroup)
+	if err := c.Do(ctx, req, &r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// see: https://docs.sendgrid.com/api-reference/suppressions-suppressions/delete-a-suppression-from-a-suppression-group
+func (c *Client) DeleteSuppressionFromGroup(ctx context.Context, id int64, email string) error {
+	path := fmt.Sprintf("/asm/groups/%s/suppressions/%s", strconv.FormatInt(id, 10), url.QueryEscape(email))
+
+	req, err := c.NewRequest("DELETE", path, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := c.Do(ctx, req, nil); err != nil {
+		return err
+	}
+	return nil
+}
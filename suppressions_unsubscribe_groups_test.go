@@ -353,3 +353,67 @@ func TestDeleteSuppressionGroup_NewRequestError(t *testing.T) {
 
 	client.baseURL = originalBaseURL
 }
+
+func TestGetSuppressionGroupSuppressions(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/asm/groups/12345/suppressions", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		if _, err := fmt.Fprint(w, `["a@example.com","b@example.com"]`); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	expected, err := client.GetSuppressionGroupSuppressions(context.TODO(), 12345)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+		return
+	}
+
+	want := []string{"a@example.com", "b@example.com"}
+	if !reflect.DeepEqual(want, expected) {
+		t.Fatal(ErrIncorrectResponse)
+	}
+}
+
+func TestAddSuppressionsToGroup(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/asm/groups/12345/suppressions", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPost)
+		w.WriteHeader(http.StatusCreated)
+		if _, err := fmt.Fprint(w, `{"recipient_emails":["a@example.com"]}`); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	expected, err := client.AddSuppressionsToGroup(context.TODO(), 12345, &InputAddSuppressionsToGroup{
+		RecipientEmails: []string{"a@example.com"},
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+		return
+	}
+
+	want := &OutputAddSuppressionsToGroup{RecipientEmails: []string{"a@example.com"}}
+	if !reflect.DeepEqual(want, expected) {
+		t.Fatal(ErrIncorrectResponse)
+	}
+}
+
+func TestDeleteSuppressionFromGroup(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/asm/groups/12345/suppressions/a@example.com", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodDelete)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	err := client.DeleteSuppressionFromGroup(context.TODO(), 12345, "a@example.com")
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
@@ -268,6 +268,7 @@ func (c *Client) GetTeammateSubuserAccess(ctx context.Context, teammateName stri
 	if input.Username != "" {
 		q.Set("username", input.Username)
 	}
+	u.RawQuery = q.Encode()
 
 	req, err := c.NewRequest("GET", u.String(), nil)
 	if err != nil {
@@ -0,0 +1,271 @@
+package sendgrid
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// HeaderListUnsubscribe is the List-Unsubscribe header of RFC 2369.
+	HeaderListUnsubscribe = "List-Unsubscribe"
+	// HeaderListUnsubscribePost is the List-Unsubscribe-Post header of
+	// RFC 8058.
+	HeaderListUnsubscribePost = "List-Unsubscribe-Post"
+	// ListUnsubscribeOneClick is the value of List-Unsubscribe-Post, and of
+	// the form field posted by mailbox providers, for one-click unsubscribe.
+	ListUnsubscribeOneClick = "List-Unsubscribe=One-Click"
+)
+
+// maxUnsubscribeBody is the largest one-click request body that
+// UnsubscribeHandler reads.
+const maxUnsubscribeBody = 1 << 16
+
+// ListUnsubscribe generates signed per-recipient List-Unsubscribe headers,
+// as required by the bulk sender rules of Gmail and Yahoo, and verifies
+// them in UnsubscribeHandler.
+//
+// Each recipient gets a token that holds their address and the suppression
+// group to add them to, signed with Secret using HMAC-SHA256. The token is
+// passed in the token query parameter of URL, and in the subject of the
+// mailto link as "unsubscribe <token>".
+type ListUnsubscribe struct {
+	// URL is the HTTPS endpoint serving UnsubscribeHandler.
+	URL string
+	// Mailto is an optional address that receives unsubscribe requests by
+	// mail, for example through the Inbound Parse Webhook.
+	Mailto string
+	// Secret is the key used to sign tokens.
+	Secret []byte
+	// TTL is how long tokens stay valid. Zero means tokens do not expire.
+	TTL time.Duration
+}
+
+// UnsubscribeToken is the content of a verified unsubscribe token.
+type UnsubscribeToken struct {
+	Email   string
+	GroupID int64
+	// Expires is zero if the token does not expire.
+	Expires time.Time
+}
+
+type unsubscribePayload struct {
+	Email   string `json:"e"`
+	GroupID int64  `json:"g"`
+	Expires int64  `json:"x,omitempty"`
+}
+
+// Token returns the signed token that unsubscribes email from the
+// suppression group groupID.
+func (l *ListUnsubscribe) Token(email string, groupID int64) (string, error) {
+	t := UnsubscribeToken{Email: email, GroupID: groupID}
+	if l.TTL > 0 {
+		t.Expires = time.Now().Add(l.TTL)
+	}
+	return l.sign(t)
+}
+
+func (l *ListUnsubscribe) sign(t UnsubscribeToken) (string, error) {
+	if len(l.Secret) == 0 {
+		return "", errors.New("list-unsubscribe secret is required")
+	}
+	if t.Email == "" {
+		return "", errors.New("list-unsubscribe email is required")
+	}
+	p := unsubscribePayload{Email: t.Email, GroupID: t.GroupID}
+	if !t.Expires.IsZero() {
+		p.Expires = t.Expires.Unix()
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(l.mac(payload)), nil
+}
+
+func (l *ListUnsubscribe) mac(payload string) []byte {
+	h := hmac.New(sha256.New, l.Secret)
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
+
+// Verify checks the signature and expiry of token and returns its content.
+func (l *ListUnsubscribe) Verify(token string) (*UnsubscribeToken, error) {
+	if len(l.Secret) == 0 {
+		return nil, errors.New("list-unsubscribe secret is required")
+	}
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, errors.New("malformed unsubscribe token")
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, l.mac(payload)) {
+		return nil, errors.New("invalid unsubscribe token signature")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, errors.New("malformed unsubscribe token")
+	}
+	var p unsubscribePayload
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, errors.New("malformed unsubscribe token")
+	}
+
+	t := &UnsubscribeToken{Email: p.Email, GroupID: p.GroupID}
+	if p.Expires != 0 {
+		t.Expires = time.Unix(p.Expires, 0)
+		if time.Now().After(t.Expires) {
+			return nil, errors.New("unsubscribe token has expired")
+		}
+	}
+	return t, nil
+}
+
+// URLFor returns the one-click unsubscribe URL for email and groupID.
+func (l *ListUnsubscribe) URLFor(email string, groupID int64) (string, error) {
+	u, err := url.Parse(l.URL)
+	if err != nil {
+		return "", fmt.Errorf("list-unsubscribe url: %w", err)
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return "", fmt.Errorf("list-unsubscribe url %q must be an absolute https URL", l.URL)
+	}
+	token, err := l.Token(email, groupID)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// MailtoFor returns the mailto unsubscribe link for email and groupID, or
+// an empty string if Mailto is not set.
+func (l *ListUnsubscribe) MailtoFor(email string, groupID int64) (string, error) {
+	if l.Mailto == "" {
+		return "", nil
+	}
+	token, err := l.Token(email, groupID)
+	if err != nil {
+		return "", err
+	}
+	return "mailto:" + l.Mailto + "?subject=" + url.PathEscape("unsubscribe "+token), nil
+}
+
+// Headers returns the List-Unsubscribe and List-Unsubscribe-Post headers
+// for email and groupID.
+func (l *ListUnsubscribe) Headers(email string, groupID int64) (map[string]string, error) {
+	link, err := l.URLFor(email, groupID)
+	if err != nil {
+		return nil, err
+	}
+	value := "<" + link + ">"
+	mailto, err := l.MailtoFor(email, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if mailto != "" {
+		value += ", <" + mailto + ">"
+	}
+	return map[string]string{
+		HeaderListUnsubscribe:     value,
+		HeaderListUnsubscribePost: ListUnsubscribeOneClick,
+	}, nil
+}
+
+// SetListUnsubscribe sets the List-Unsubscribe headers of p for its
+// recipient. p must have exactly one To recipient.
+func (p *Personalization) SetListUnsubscribe(l *ListUnsubscribe, groupID int64) error {
+	if len(p.To) != 1 || p.To[0] == nil {
+		return fmt.Errorf("list-unsubscribe requires exactly one to recipient, got %d", len(p.To))
+	}
+	headers, err := l.Headers(p.To[0].Email, groupID)
+	if err != nil {
+		return err
+	}
+	if p.Headers == nil {
+		p.Headers = map[string]string{}
+	}
+	for k, v := range headers {
+		p.Headers[k] = v
+	}
+	return nil
+}
+
+// SetListUnsubscribe sets the ASM group of m to groupID and the
+// List-Unsubscribe headers of each personalization of m. It fails if m
+// already has a different ASM group.
+func (m *InputSendMail) SetListUnsubscribe(l *ListUnsubscribe, groupID int64) error {
+	if m.ASM != nil && int64(m.ASM.GroupID) != groupID {
+		return fmt.Errorf("asm group_id %d does not match %d", m.ASM.GroupID, groupID)
+	}
+	for i, p := range m.Personalizations {
+		if p == nil {
+			continue
+		}
+		if err := p.SetListUnsubscribe(l, groupID); err != nil {
+			return fmt.Errorf("personalization %d: %w", i, err)
+		}
+	}
+	if m.ASM == nil {
+		m.ASM = &ASM{GroupID: int(groupID)}
+	}
+	return nil
+}
+
+// UnsubscribeHandler is an http.Handler for the one-click unsubscribe
+// requests of RFC 8058. It verifies the token of the request URL and adds
+// the recipient to the suppression group of the token.
+//
+// Only POST requests with the List-Unsubscribe=One-Click form field are
+// processed, so that link scanners following the URL with GET do not
+// unsubscribe recipients.
+type UnsubscribeHandler struct {
+	client *Client
+	list   *ListUnsubscribe
+}
+
+// NewUnsubscribeHandler returns an UnsubscribeHandler that verifies tokens
+// with l and adds suppressions with c.
+func NewUnsubscribeHandler(c *Client, l *ListUnsubscribe) *UnsubscribeHandler {
+	return &UnsubscribeHandler{client: c, list: l}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *UnsubscribeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUnsubscribeBody)
+	field, value, _ := strings.Cut(ListUnsubscribeOneClick, "=")
+	if r.PostFormValue(field) != value {
+		http.Error(w, "missing "+ListUnsubscribeOneClick, http.StatusBadRequest)
+		return
+	}
+
+	t, err := h.list.Verify(r.URL.Query().Get("token"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	input := &InputAddSuppressionsToGroup{RecipientEmails: []string{t.Email}}
+	if _, err := h.client.AddSuppressionsToGroup(r.Context(), t.GroupID, input); err != nil {
+		h.client.Debugf("unsubscribe from group %d: %v", t.GroupID, err)
+		http.Error(w, "unsubscribe failed", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,158 @@
+package sendgrid
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testListUnsubscribe() *ListUnsubscribe {
+	return &ListUnsubscribe{
+		URL:    "https://example.com/unsubscribe?list=news",
+		Mailto: "unsubscribe@example.com",
+		Secret: []byte("secret"),
+	}
+}
+
+func TestListUnsubscribe_Verify(t *testing.T) {
+	l := testListUnsubscribe()
+	token, err := l.Token("a@example.com", 42)
+	assert.NoError(t, err)
+
+	got, err := l.Verify(token)
+	assert.NoError(t, err)
+	assert.Equal(t, &UnsubscribeToken{Email: "a@example.com", GroupID: 42}, got)
+
+	other := &ListUnsubscribe{Secret: []byte("other")}
+	_, err = other.Verify(token)
+	assert.EqualError(t, err, "invalid unsubscribe token signature")
+
+	_, err = l.Verify("garbage")
+	assert.EqualError(t, err, "malformed unsubscribe token")
+
+	expired, err := l.sign(UnsubscribeToken{Email: "a@example.com", GroupID: 42, Expires: time.Now().Add(-time.Minute)})
+	assert.NoError(t, err)
+	_, err = l.Verify(expired)
+	assert.EqualError(t, err, "unsubscribe token has expired")
+
+	l.TTL = time.Hour
+	token, err = l.Token("a@example.com", 42)
+	assert.NoError(t, err)
+	got, err = l.Verify(token)
+	assert.NoError(t, err)
+	assert.False(t, got.Expires.IsZero())
+
+	_, err = (&ListUnsubscribe{}).Token("a@example.com", 42)
+	assert.EqualError(t, err, "list-unsubscribe secret is required")
+}
+
+func TestListUnsubscribe_Headers(t *testing.T) {
+	l := testListUnsubscribe()
+	headers, err := l.Headers("a@example.com", 42)
+	assert.NoError(t, err)
+	assert.Equal(t, ListUnsubscribeOneClick, headers[HeaderListUnsubscribePost])
+
+	links := strings.Split(headers[HeaderListUnsubscribe], ", ")
+	if !assert.Len(t, links, 2) {
+		return
+	}
+	u, err := url.Parse(strings.Trim(links[0], "<>"))
+	assert.NoError(t, err)
+	assert.Equal(t, "https", u.Scheme)
+	assert.Equal(t, "news", u.Query().Get("list"))
+	token, err := l.Verify(u.Query().Get("token"))
+	assert.NoError(t, err)
+	assert.Equal(t, "a@example.com", token.Email)
+
+	mailto, err := url.Parse(strings.Trim(links[1], "<>"))
+	assert.NoError(t, err)
+	assert.Equal(t, "unsubscribe@example.com", mailto.Opaque)
+	subject, ok := strings.CutPrefix(mailto.Query().Get("subject"), "unsubscribe ")
+	assert.True(t, ok)
+	_, err = l.Verify(subject)
+	assert.NoError(t, err)
+
+	l.URL = "http://example.com/unsubscribe"
+	_, err = l.Headers("a@example.com", 42)
+	assert.Error(t, err)
+}
+
+func TestInputSendMail_SetListUnsubscribe(t *testing.T) {
+	l := testListUnsubscribe()
+	m := NewInputSendMail()
+	for _, email := range []string{"a@example.com", "b@example.com"} {
+		p := NewPersonalization()
+		p.AddTo(NewEmail(email, ""))
+		m.AddPersonalization(p)
+	}
+
+	assert.NoError(t, m.SetListUnsubscribe(l, 42))
+	assert.Equal(t, &ASM{GroupID: 42}, m.ASM)
+	for i, email := range []string{"a@example.com", "b@example.com"} {
+		header := m.Personalizations[i].Headers[HeaderListUnsubscribe]
+		u, err := url.Parse(strings.Trim(strings.Split(header, ", ")[0], "<>"))
+		assert.NoError(t, err)
+		token, err := l.Verify(u.Query().Get("token"))
+		assert.NoError(t, err)
+		assert.Equal(t, email, token.Email)
+	}
+
+	assert.EqualError(t, m.SetListUnsubscribe(l, 7), "asm group_id 42 does not match 7")
+
+	m.Personalizations[1].AddTo(NewEmail("c@example.com", ""))
+	assert.EqualError(t, m.SetListUnsubscribe(l, 42), "personalization 1: list-unsubscribe requires exactly one to recipient, got 2")
+}
+
+func TestUnsubscribeHandler(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	var added []string
+	mux.HandleFunc("/asm/groups/42/suppressions", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPost)
+		var in InputAddSuppressionsToGroup
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			t.Fatal(err)
+		}
+		added = append(added, in.RecipientEmails...)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	l := testListUnsubscribe()
+	h := NewUnsubscribeHandler(client, l)
+	link, err := l.URLFor("a@example.com", 42)
+	assert.NoError(t, err)
+
+	serve := func(method, target, contentType string, body []byte) int {
+		req := httptest.NewRequest(method, target, bytes.NewReader(body)).WithContext(context.TODO())
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(http.MethodGet, link, "", nil))
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, link, "application/x-www-form-urlencoded", nil))
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodPost, "https://example.com/unsubscribe?token=x.y", "application/x-www-form-urlencoded", []byte(ListUnsubscribeOneClick)))
+	assert.Empty(t, added)
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, link, "application/x-www-form-urlencoded", []byte(ListUnsubscribeOneClick)))
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	assert.NoError(t, mw.WriteField("List-Unsubscribe", "One-Click"))
+	assert.NoError(t, mw.Close())
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, link, mw.FormDataContentType(), buf.Bytes()))
+	assert.Equal(t, []string{"a@example.com", "a@example.com"}, added)
+}